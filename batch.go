@@ -0,0 +1,191 @@
+package jigsaw
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CutOptions are the choices made when cutting an image, shared by every image of a batch
+type CutOptions struct {
+	Pieces int
+	Rows   int
+	Seed   int64
+	Rotate bool
+	Fit    *Fit
+	Cutter JigsawPieceCutter
+	Format string
+	// MinTabSize, MaxTabSize, TabJitter, EdgeStyle, WaveAmplitude, Tiling, Whimsies, Saliency, Mode, Back,
+	// Bevel, Stroke and Shadow are as on JigsawBuilder
+	MinTabSize    float64
+	MaxTabSize    float64
+	TabJitter     float64
+	EdgeStyle     string
+	WaveAmplitude float64
+	Tiling        Tiling
+	Whimsies      []Whimsy
+	Saliency      *Saliency
+	Mode          string
+	Back          image.Image
+	Bevel         *Bevel
+	Stroke        *Stroke
+	Shadow        *Shadow
+}
+
+// Cut cuts the image into a jigsaw
+func (o CutOptions) Cut(img image.Image) (Jigsaw, error) {
+	builder := NewJigsawBuilderWithPieceCutter(img, o.Pieces, o.Cutter)
+	builder.NumRows = o.Rows
+	builder.Seed = o.Seed
+	builder.Rotate = o.Rotate
+	builder.Fit = o.Fit
+	builder.MinTabSize = o.MinTabSize
+	builder.MaxTabSize = o.MaxTabSize
+	builder.TabJitter = o.TabJitter
+	builder.EdgeStyle = o.EdgeStyle
+	builder.WaveAmplitude = o.WaveAmplitude
+	builder.Tiling = o.Tiling
+	builder.Whimsies = o.Whimsies
+	builder.Saliency = o.Saliency
+	builder.Mode = o.Mode
+	builder.Back = o.Back
+	builder.Bevel = o.Bevel
+	builder.Stroke = o.Stroke
+	builder.Shadow = o.Shadow
+	return builder.Build()
+}
+
+// CutFile loads the image at path, cuts it and exports it into dir in the options' Format
+func CutFile(path, dir string, opts CutOptions) (Jigsaw, error) {
+	exporter, err := NewExporter(opts.Format)
+	if err != nil {
+		return Jigsaw{}, err
+	}
+	img, err := LoadImage(path)
+	if err != nil {
+		return Jigsaw{}, err
+	}
+	jig, err := opts.Cut(img)
+	if err != nil {
+		return jig, fmt.Errorf("could not cut %s: %s", path, err)
+	}
+	if err := exporter.Export(jig, dir); err != nil {
+		return jig, fmt.Errorf("could not write %s: %s", dir, err)
+	}
+	return jig, nil
+}
+
+// BatchResult is what happened to one image of a batch. Error is empty when it was cut
+type BatchResult struct {
+	Input    string        `json:"input"`
+	Output   string        `json:"output"`
+	Pieces   int           `json:"pieces,omitempty"`
+	Duration time.Duration `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// BatchReport lists the result of every image of a batch ordered by input path
+type BatchReport struct {
+	Succeeded int           `json:"succeeded"`
+	Failed    int           `json:"failed"`
+	Results   []BatchResult `json:"results"`
+}
+
+// CutBatch cuts every image found under inDir with the same options, writing each one to a folder of
+// outDir with the same relative path as the image minus its extension. Images that would share a folder,
+// such as photo.jpg and photo.png, are not cut after the first and are failed in the report. Up to workers
+// images are cut at a time, 0 uses one per cpu. An image that can not be cut is recorded in the report and
+// the rest carry on, the error is only for when inDir can not be read
+func CutBatch(inDir, outDir string, opts CutOptions, workers int) (BatchReport, error) {
+	if _, err := NewExporter(opts.Format); err != nil {
+		return BatchReport{}, err
+	}
+	inputs := make([]string, 0)
+	err := filepath.Walk(inDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() && IsImageFile(path) {
+			inputs = append(inputs, path)
+		}
+		return nil
+	})
+	if err != nil {
+		return BatchReport{}, err
+	}
+	if workers < 1 {
+		workers = runtime.NumCPU()
+	}
+
+	report := BatchReport{Results: make([]BatchResult, len(inputs))}
+	//images are walked in order so the first to claim a folder is the same every time
+	claimed := make(map[string]string, len(inputs))
+	todo := make([]int, 0, len(inputs))
+	for i, path := range inputs {
+		out := batchOutput(inDir, path, outDir)
+		if first, ok := claimed[out]; ok {
+			report.Results[i] = BatchResult{Input: path, Output: out, Error: fmt.Sprintf("could not cut %s: %s is already written by %s", path, out, first)}
+			continue
+		}
+		claimed[out] = path
+		todo = append(todo, i)
+	}
+	next := make(chan int)
+	wg := sync.WaitGroup{}
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				report.Results[i] = cutBatchFile(inputs[i], batchOutput(inDir, inputs[i], outDir), opts)
+			}
+		}()
+	}
+	for _, i := range todo {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+
+	for _, r := range report.Results {
+		if r.Error == "" {
+			report.Succeeded++
+		} else {
+			report.Failed++
+		}
+	}
+	return report, nil
+}
+
+// batchOutput is the folder of outDir an image of inDir is written to
+func batchOutput(inDir, path, outDir string) string {
+	rel, err := filepath.Rel(inDir, path)
+	if err != nil {
+		rel = filepath.Base(path)
+	}
+	return filepath.Join(outDir, strings.TrimSuffix(rel, filepath.Ext(rel)))
+}
+
+func cutBatchFile(path, output string, opts CutOptions) (result BatchResult) {
+	result = BatchResult{Input: path, Output: output}
+	start := time.Now()
+	defer func() {
+		//one bad image must not take the rest of the batch down with it
+		if r := recover(); r != nil {
+			result.Error = fmt.Sprintf("could not cut %s: %v", path, r)
+		}
+		result.Duration = time.Since(start)
+	}()
+	jig, err := CutFile(path, result.Output, opts)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Pieces = len(jig.Pieces)
+	return result
+}
@@ -0,0 +1,75 @@
+package jigsaw_test
+
+import (
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func writePNG(t *testing.T, path string, w, h int) {
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	assert.NoError(t, png.Encode(f, image.NewRGBA(image.Rect(0, 0, w, h))))
+}
+
+func TestCutBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	in, out := filepath.Join(dir, "in"), filepath.Join(dir, "out")
+	writePNG(t, filepath.Join(in, "a.png"), 80, 80)
+	writePNG(t, filepath.Join(in, "nested", "b.png"), 120, 60)
+	writePNG(t, filepath.Join(in, "tiny.png"), 1, 1)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(in, "broken.jpg"), []byte("not a jpeg"), 0644))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(in, "notes.txt"), []byte("ignored"), 0644))
+
+	report, err := jigsaw.CutBatch(in, out, jigsaw.CutOptions{Pieces: 4, Seed: 3, Format: jigsaw.FORMAT_DIR}, 2)
+	assert.NoError(t, err, "did not expect the batch to fail")
+	assert.Equal(t, 2, report.Succeeded)
+	assert.Equal(t, 2, report.Failed)
+	if assert.Len(t, report.Results, 4) {
+		results := map[string]jigsaw.BatchResult{}
+		for _, r := range report.Results {
+			rel, _ := filepath.Rel(in, r.Input)
+			results[rel] = r
+		}
+		assert.Empty(t, results["a.png"].Error)
+		assert.Equal(t, 4, results["a.png"].Pieces)
+		assert.Equal(t, filepath.Join(out, "nested", "b"), results[filepath.Join("nested", "b.png")].Output)
+		assert.Contains(t, results["broken.jpg"].Error, "could not decode")
+		assert.Contains(t, results["tiny.png"].Error, "too small")
+	}
+	_, err = os.Stat(filepath.Join(out, "nested", "b", jigsaw.MANIFEST_FILE))
+	assert.NoError(t, err, "expected the nested image to be written to its own folder")
+}
+
+func TestCutBatchMissingDirectory(t *testing.T) {
+	_, err := jigsaw.CutBatch(filepath.Join(os.TempDir(), "no-such-batch-dir"), os.TempDir(), jigsaw.CutOptions{Pieces: 4, Format: jigsaw.FORMAT_DIR}, 1)
+	assert.Error(t, err)
+}
+
+func TestCutBatchSameFolder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	in, out := filepath.Join(dir, "in"), filepath.Join(dir, "out")
+	writePNG(t, filepath.Join(in, "photo.png"), 80, 80)
+	writePNG(t, filepath.Join(in, "photo.tif"), 80, 80)
+
+	report, err := jigsaw.CutBatch(in, out, jigsaw.CutOptions{Pieces: 4, Format: jigsaw.FORMAT_DIR}, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, report.Succeeded)
+	assert.Equal(t, 1, report.Failed)
+	if assert.Len(t, report.Results, 2) {
+		assert.Empty(t, report.Results[0].Error, "expected the first image to be cut")
+		assert.Contains(t, report.Results[1].Error, "already written by "+filepath.Join(in, "photo.png"))
+	}
+}
@@ -0,0 +1,100 @@
+package jigsaw
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+)
+
+// DEFAULT_BEVEL_ANGLE is where the light comes from, the top left, and DEFAULT_BEVEL_DEPTH how many pixels
+// in from its edge a piece is bevelled
+const (
+	DEFAULT_BEVEL_ANGLE = 135.0
+	DEFAULT_BEVEL_DEPTH = 4
+)
+
+// how far the side of a bevel facing the light is lightened towards white, and the side facing away darkened
+// towards black, where it is steepest
+const bevelLight = 0.6
+
+// Bevel shades the edges of each piece as though it were cardboard lit from one side. Angle is the direction
+// the light comes from in degrees counter clockwise from the right of the board and Depth how many pixels
+// in from the edge the bevel goes, 0 uses DEFAULT_BEVEL_DEPTH. Pieces are lit as they lie once rotated, so
+// the light gives nothing away about which way up they go
+type Bevel struct {
+	Angle float64
+	Depth int
+}
+
+func (b *Bevel) validate() error {
+	if b != nil && b.Depth < 0 {
+		return errors.New("bevel depth must not be negative")
+	}
+	return nil
+}
+
+// shade bevels the shape of the image, its pixels that are more than half opaque. The height of each pixel
+// rises from the edge of the shape to Depth in from it and is lit by how much it slopes towards the light
+func (b Bevel) shade(img image.Image) image.Image {
+	depth := b.Depth
+	if depth == 0 {
+		depth = DEFAULT_BEVEL_DEPTH
+	}
+	r := img.Bounds()
+	w, h := r.Dx(), r.Dy()
+	//the distance of every pixel from the edge of the shape, stopping at the depth
+	dist := make([]int, w*h)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if _, _, _, a := img.At(r.Min.X+x, r.Min.Y+y).RGBA(); a >= 0x8000 {
+				dist[y*w+x] = depth
+			}
+		}
+	}
+	at := func(x, y int) int {
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return 0
+		}
+		return dist[y*w+x]
+	}
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			dist[y*w+x] = minInt(dist[y*w+x], minInt(at(x-1, y), at(x, y-1))+1)
+		}
+	}
+	for y := h - 1; y >= 0; y-- {
+		for x := w - 1; x >= 0; x-- {
+			dist[y*w+x] = minInt(dist[y*w+x], minInt(at(x+1, y), at(x, y+1))+1)
+		}
+	}
+	angle := b.Angle * math.Pi / 180
+	//y runs down the image
+	lx, ly := math.Cos(angle), -math.Sin(angle)
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.NRGBAModel.Convert(img.At(r.Min.X+x, r.Min.Y+y)).(color.NRGBA)
+			if dist[y*w+x] > 0 {
+				//the distance climbs by one a pixel, so the bevel sloping straight up towards the light is 1
+				gx := float64(at(x+1, y)-at(x-1, y)) / 2
+				gy := float64(at(x, y+1)-at(x, y-1)) / 2
+				light := math.Max(-1, math.Min(1, -(gx*lx+gy*ly)))
+				c.R, c.G, c.B = lit(c.R, light), lit(c.G, light), lit(c.B, light)
+			}
+			out.SetNRGBA(x, y, c)
+		}
+	}
+	return out
+}
+
+// lit lightens or darkens one channel of a colour by how much light falls on it, from -1 to 1
+func lit(v uint8, light float64) uint8 {
+	f := float64(v) / 0xff
+	if light > 0 {
+		f += (1 - f) * light * bevelLight
+	} else {
+		f *= 1 + light*bevelLight
+	}
+	return uint8(f*0xff + 0.5)
+}
@@ -0,0 +1,76 @@
+package jigsaw_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func bevelledJigsaw(t *testing.T, bevel *jigsaw.Bevel, cutter jigsaw.JigsawPieceCutter) jigsaw.Jigsaw {
+	return buildJigsaw(t, filled(160, 160, color.RGBA{0x80, 0x80, 0x80, 0xff}), 16, cutter, func(b *jigsaw.JigsawBuilder) {
+		b.Bevel = bevel
+	})
+}
+
+func grey(img image.Image, x, y int) uint8 {
+	r, _, _, _ := img.At(x, y).RGBA()
+	return uint8(r >> 8)
+}
+
+func TestBevelLightsTheSideFacingTheLight(t *testing.T) {
+	flat := jigsaw.JigsawPieceCutter{JointStyle: jigsaw.JOINT_FLAT}
+	img := bevelledJigsaw(t, &jigsaw.Bevel{Angle: 180, Depth: 5}, flat).Pieces[5].Image
+	assert.True(t, grey(img, 0, 20) > 0x80, "expected the left edge to be lit, got %#x", grey(img, 0, 20))
+	assert.True(t, grey(img, 39, 20) < 0x80, "expected the right edge to be in shadow, got %#x", grey(img, 39, 20))
+	assert.Equal(t, uint8(0x80), grey(img, 20, 0), "expected edges along the light to be left alone")
+	assert.Equal(t, uint8(0x80), grey(img, 20, 20), "expected the middle to be flat")
+	assert.Equal(t, uint8(0x80), grey(img, 6, 20), "expected nothing past the depth to be shaded")
+
+	img = bevelledJigsaw(t, &jigsaw.Bevel{Angle: 270}, flat).Pieces[5].Image
+	assert.True(t, grey(img, 20, 39) > 0x80, "expected light from below to light the bottom edge")
+	assert.True(t, grey(img, 20, 0) < 0x80)
+	assert.Equal(t, uint8(0x80), grey(img, 20, 5), "expected the default depth to be used")
+}
+
+func TestBevelLightsRotatedPiecesAlike(t *testing.T) {
+	jig := buildJigsaw(t, filled(160, 160, color.RGBA{0x80, 0x80, 0x80, 0xff}), 16, jigsaw.JigsawPieceCutter{JointStyle: jigsaw.JOINT_FLAT}, func(b *jigsaw.JigsawBuilder) {
+		b.Bevel = &jigsaw.Bevel{Angle: 180}
+		b.Rotate = true
+		b.Seed = 6
+	})
+	turned := 0
+	for _, p := range jig.Pieces {
+		if p.Rotation != 0 {
+			turned++
+		}
+		assert.True(t, grey(p.Image, 0, 20) > 0x80, "expected the left of piece %d turned %d to be lit", p.Index, p.Rotation)
+		assert.True(t, grey(p.Image, 39, 20) < 0x80, "expected the right of piece %d turned %d to be in shadow", p.Index, p.Rotation)
+	}
+	assert.True(t, turned > 0, "expected some pieces to be turned")
+}
+
+func TestBevelKeepsTheShape(t *testing.T) {
+	plain := bevelledJigsaw(t, nil, jigsaw.JigsawPieceCutter{})
+	bevelled := bevelledJigsaw(t, &jigsaw.Bevel{Angle: jigsaw.DEFAULT_BEVEL_ANGLE}, jigsaw.JigsawPieceCutter{})
+	for i, p := range bevelled.Pieces {
+		assert.Equal(t, plain.Pieces[i].Bounds, p.Bounds)
+		b := p.Bounds
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				if alpha(p, x, y) != alpha(plain.Pieces[i], x, y) {
+					t.Fatalf("expected piece %d to keep its shape at %d,%d", p.Index, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestBevelDepthValidated(t *testing.T) {
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(filled(100, 100, color.White), 4, jigsaw.JigsawPieceCutter{})
+	builder.Bevel = &jigsaw.Bevel{Depth: -1}
+	_, err := builder.Build()
+	assert.Error(t, err)
+}
@@ -0,0 +1,527 @@
+// Command jigsaw cuts images into jigsaws.
+//
+//	jigsaw cut -in photo.jpg -pieces 16 -format dir -out ./out
+//	jigsaw batch -in ./photos -pieces 100 -format zip -out ./puzzles
+//
+// Run a subcommand with -h to see all of its options.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/maleck13/jigsaw"
+)
+
+const usage = `usage: jigsaw <command> [options]
+
+commands:
+  cut    cut an image into a jigsaw
+  batch  cut every image in a directory into jigsaws
+`
+
+// the layouts the pieces can be cut in
+const (
+	layoutGrid     = "grid"
+	layoutVoronoi  = "voronoi"
+	layoutHex      = "hex"
+	layoutTriangle = "triangle"
+)
+
+// errUsage is returned once the problem with the arguments has already been reported
+var errUsage = errors.New("bad usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run runs the command line and returns the exit code, 2 for bad arguments and 1 when the work fails
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	var err error
+	switch args[0] {
+	case "cut":
+		err = cut(args[1:], stdout, stderr)
+	case "batch":
+		err = batch(args[1:], stdout, stderr)
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(stdout, usage)
+		return 0
+	default:
+		fmt.Fprintf(stderr, "jigsaw: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+	if err == errUsage {
+		return 2
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "jigsaw:", err)
+		return 1
+	}
+	return 0
+}
+
+// cutOptions are the flags shared by everything that cuts images
+type cutOptions struct {
+	pieces, rows, cols int
+	seed               int64
+	joints             string
+	tab                float64
+	tabMax             float64
+	jitter             float64
+	edges              string
+	wave               float64
+	layout             string
+	relax              int
+	whimsyFlags        listFlag
+	whimsyFiles        []string
+	whimsies           []jigsaw.Whimsy
+	detail             bool
+	protect            string
+	shift              float64
+	saliency           *jigsaw.Saliency
+	mode               string
+	backPath           string
+	back               image.Image
+	bevel              bool
+	bevelAngle         float64
+	bevelDepth         int
+	strokeColour       string
+	strokeWidth        int
+	stroke             *jigsaw.Stroke
+	dropShadow         bool
+	shadowOffset       string
+	shadowBlur         float64
+	shadow             *jigsaw.Shadow
+	rotate             bool
+	maxSize            int
+	aspect             string
+	pad                bool
+	background         string
+	fit                *jigsaw.Fit
+	format             string
+	out                string
+}
+
+func (o *cutOptions) register(flags *flag.FlagSet) {
+	flags.IntVar(&o.pieces, "pieces", 0, "number of pieces, needs a whole number of rows unless -rows or -cols is given")
+	flags.IntVar(&o.rows, "rows", 0, "number of rows of pieces")
+	flags.IntVar(&o.cols, "cols", 0, "number of columns of pieces")
+	flags.Int64Var(&o.seed, "seed", 0, "seed for the random choices, 0 picks one")
+	flags.StringVar(&o.joints, "joints", jigsaw.JOINT_ROUND, "joint style: round, square or flat")
+	flags.Float64Var(&o.tab, "tab", jigsaw.PERCENTAGE, "tab size as a percentage of the longer side of a piece")
+	flags.Float64Var(&o.tabMax, "tab-max", 0, "vary each tab's size between -tab and this")
+	flags.Float64Var(&o.jitter, "jitter", 0, "move each tab along its edge by up to this fraction of the edge")
+	flags.StringVar(&o.edges, "edges", jigsaw.EDGE_STRAIGHT, "edge style: straight or wavy")
+	flags.Float64Var(&o.wave, "wave", jigsaw.DEFAULT_WAVE_AMPLITUDE, "how far wavy edges move as a percentage of their length")
+	flags.StringVar(&o.layout, "layout", layoutGrid, "how the pieces are laid out: grid, voronoi, hex or triangle")
+	flags.IntVar(&o.relax, "relax", jigsaw.DEFAULT_RELAX, "rounds of relaxation evening out voronoi pieces")
+	flags.Var(&o.whimsyFlags, "whimsy", "cut a whimsy shaped like a png or svg at a place on the board, as heart.svg@40,60, can be repeated")
+	flags.BoolVar(&o.detail, "saliency", false, "move grid lines and tabs away from the detailed parts of the image")
+	flags.StringVar(&o.protect, "protect", "", "png or svg whose opaque parts grid lines and tabs are kept away from")
+	flags.Float64Var(&o.shift, "shift", jigsaw.DEFAULT_SALIENCY_SHIFT, "how far -saliency and -protect can move a grid line as a fraction of a piece")
+	flags.StringVar(&o.mode, "mode", jigsaw.MODE_ALL, "which pieces to keep: all, edge for the frame or center for the rest")
+	flags.StringVar(&o.backPath, "back", "", "image the same size as the one being cut to put on the back of every piece")
+	flags.BoolVar(&o.bevel, "bevel", false, "shade the edges of the pieces so they look like lit cardboard")
+	flags.Float64Var(&o.bevelAngle, "bevel-angle", jigsaw.DEFAULT_BEVEL_ANGLE, "where the light on -bevel comes from in degrees counter clockwise from the right")
+	flags.IntVar(&o.bevelDepth, "bevel-depth", jigsaw.DEFAULT_BEVEL_DEPTH, "how many pixels in from the edge -bevel shades")
+	flags.StringVar(&o.strokeColour, "stroke", "", "outline every piece in a colour as #rrggbb")
+	flags.IntVar(&o.strokeWidth, "stroke-width", jigsaw.DEFAULT_STROKE_WIDTH, "how many pixels wide -stroke is")
+	flags.BoolVar(&o.dropShadow, "shadow", false, "drop a shadow behind every piece, growing its image")
+	flags.StringVar(&o.shadowOffset, "shadow-offset", "3,3", "how far the -shadow falls from its piece as x,y")
+	flags.Float64Var(&o.shadowBlur, "shadow-blur", jigsaw.DEFAULT_SHADOW_BLUR, "how soft the -shadow is")
+	flags.BoolVar(&o.rotate, "rotate", false, "randomly rotate the pieces")
+	flags.IntVar(&o.maxSize, "max-size", 0, "scale the image down so its longest side is at most this")
+	flags.StringVar(&o.aspect, "aspect", "", "crop the image to an aspect ratio such as 4:3 or 1.5")
+	flags.BoolVar(&o.pad, "pad", false, "pad the image instead of cropping it to the aspect and grid")
+	flags.StringVar(&o.background, "background", "", "colour to pad with as #rrggbb, transparent when not given")
+	flags.StringVar(&o.format, "format", jigsaw.FORMAT_DIR, "output format: dir, zip, atlas or svg")
+	flags.StringVar(&o.out, "out", "out", "directory to write the puzzle to")
+}
+
+// validate works out the grid and checks everything that can be checked before any file is read
+func (o *cutOptions) validate() error {
+	if o.pieces < 0 || o.rows < 0 || o.cols < 0 {
+		return errors.New("-pieces, -rows and -cols must not be negative")
+	}
+	if err := o.validateLayout(); err != nil {
+		return err
+	}
+	switch {
+	case o.layout != layoutGrid:
+	case o.rows > 0 && o.cols > 0:
+		if o.pieces > 0 && o.pieces != o.rows*o.cols {
+			return fmt.Errorf("-pieces %d does not match -rows %d and -cols %d", o.pieces, o.rows, o.cols)
+		}
+		o.pieces = o.rows * o.cols
+	case o.pieces == 0:
+		return errors.New("give the number of -pieces or both -rows and -cols")
+	case o.rows > 0 && o.pieces%o.rows != 0:
+		return fmt.Errorf("-pieces %d cannot be split into %d rows", o.pieces, o.rows)
+	case o.cols > 0:
+		if o.pieces%o.cols != 0 {
+			return fmt.Errorf("-pieces %d cannot be split into %d columns", o.pieces, o.cols)
+		}
+		o.rows = o.pieces / o.cols
+	}
+	if err := o.parseFit(); err != nil {
+		return err
+	}
+	if err := o.parseWhimsies(); err != nil {
+		return err
+	}
+	if err := o.parseSaliency(); err != nil {
+		return err
+	}
+	if o.tabMax < 0 || o.tabMax > jigsaw.MAX_TAB_SIZE || (o.tabMax > 0 && o.tabMax < o.tab) {
+		return fmt.Errorf("-tab-max should be between -tab and %v", jigsaw.MAX_TAB_SIZE)
+	}
+	if o.jitter < 0 || o.jitter > jigsaw.MAX_TAB_JITTER {
+		return fmt.Errorf("-jitter should be between 0 and %v", jigsaw.MAX_TAB_JITTER)
+	}
+	if o.edges != jigsaw.EDGE_STRAIGHT && o.edges != jigsaw.EDGE_WAVY {
+		return fmt.Errorf("unknown edge style %q, expected straight or wavy", o.edges)
+	}
+	if o.wave < 0 || o.wave > jigsaw.MAX_WAVE_AMPLITUDE {
+		return fmt.Errorf("-wave should be between 0 and %v", jigsaw.MAX_WAVE_AMPLITUDE)
+	}
+	if o.mode != jigsaw.MODE_ALL && o.mode != jigsaw.MODE_EDGE && o.mode != jigsaw.MODE_CENTER {
+		return fmt.Errorf("unknown mode %q, expected all, edge or center", o.mode)
+	}
+	if o.bevel && o.bevelDepth < 1 {
+		return errors.New("-bevel-depth must be at least 1")
+	}
+	if err := o.parseEffects(); err != nil {
+		return err
+	}
+	if err := o.cutter().Validate(); err != nil {
+		return err
+	}
+	if _, err := jigsaw.NewExporter(o.format); err != nil {
+		return err
+	}
+	if o.out == "" {
+		return errors.New("-out is required")
+	}
+	if o.seed == 0 {
+		o.seed = time.Now().UnixNano()
+	}
+	return nil
+}
+
+// load reads the whimsies, the -protect mask and the back the options name, once they have been validated
+func (o *cutOptions) load() error {
+	for i, path := range o.whimsyFiles {
+		mask, err := jigsaw.LoadSilhouette(path)
+		if err != nil {
+			return err
+		}
+		o.whimsies[i].Mask = mask
+	}
+	if o.protect != "" {
+		mask, err := jigsaw.LoadSilhouette(o.protect)
+		if err != nil {
+			return err
+		}
+		o.saliency.Protect = mask
+	}
+	if o.backPath != "" {
+		back, err := jigsaw.LoadImage(o.backPath)
+		if err != nil {
+			return err
+		}
+		o.back = back
+	}
+	return nil
+}
+
+// validateLayout checks the options that only apply to some layouts
+func (o *cutOptions) validateLayout() error {
+	switch o.layout {
+	case layoutGrid:
+		return nil
+	case layoutVoronoi, layoutHex, layoutTriangle:
+	default:
+		return fmt.Errorf("unknown layout %q, expected grid, voronoi, hex or triangle", o.layout)
+	}
+	if o.pieces == 0 || o.rows > 0 || o.cols > 0 {
+		return fmt.Errorf("-layout %s needs -pieces and can not have -rows or -cols", o.layout)
+	}
+	if o.relax < 0 {
+		return errors.New("-relax must not be negative")
+	}
+	if o.edges == jigsaw.EDGE_WAVY {
+		return jigsaw.ErrWavyTiling
+	}
+	return nil
+}
+
+// parseFit sets up the fitting of the image when any of its flags are given
+func (o *cutOptions) parseFit() error {
+	if o.maxSize == 0 && o.aspect == "" && !o.pad && o.background == "" {
+		return nil
+	}
+	if o.maxSize < 0 {
+		return errors.New("-max-size must not be negative")
+	}
+	fit := &jigsaw.Fit{MaxSize: o.maxSize, Pad: o.pad}
+	if o.aspect != "" {
+		aspect, err := parseAspect(o.aspect)
+		if err != nil {
+			return err
+		}
+		fit.Aspect = aspect
+	}
+	if o.background != "" {
+		background, err := parseColour("-background", o.background)
+		if err != nil {
+			return err
+		}
+		fit.Background = background
+	}
+	o.fit = fit
+	return nil
+}
+
+// parseWhimsies works out where each whimsy goes, their shapes are read by load
+func (o *cutOptions) parseWhimsies() error {
+	for _, f := range o.whimsyFlags {
+		at := strings.LastIndex(f, "@")
+		bad := fmt.Errorf("-whimsy %q should be a file and where it goes such as heart.svg@40,60", f)
+		if at < 0 {
+			return bad
+		}
+		xy := strings.Split(f[at+1:], ",")
+		if len(xy) != 2 {
+			return bad
+		}
+		x, errX := strconv.Atoi(xy[0])
+		y, errY := strconv.Atoi(xy[1])
+		if errX != nil || errY != nil {
+			return bad
+		}
+		o.whimsyFiles = append(o.whimsyFiles, f[:at])
+		o.whimsies = append(o.whimsies, jigsaw.Whimsy{At: image.Pt(x, y)})
+	}
+	return nil
+}
+
+// parseSaliency sets up moving the grid when -saliency or -protect is given
+func (o *cutOptions) parseSaliency() error {
+	if !o.detail && o.protect == "" {
+		return nil
+	}
+	if o.layout != layoutGrid {
+		return jigsaw.ErrSaliencyGrid
+	}
+	if o.shift <= 0 || o.shift > jigsaw.MAX_SALIENCY_SHIFT {
+		return fmt.Errorf("-shift should be more than 0 and at most %v", jigsaw.MAX_SALIENCY_SHIFT)
+	}
+	o.saliency = &jigsaw.Saliency{Detail: o.detail, Shift: o.shift}
+	return nil
+}
+
+// parseEffects sets up the outline and shadow drawn into the pieces
+func (o *cutOptions) parseEffects() error {
+	if o.strokeColour != "" {
+		colour, err := parseColour("-stroke", o.strokeColour)
+		if err != nil {
+			return err
+		}
+		if o.strokeWidth < 1 {
+			return errors.New("-stroke-width must be at least 1")
+		}
+		o.stroke = &jigsaw.Stroke{Colour: colour, Width: o.strokeWidth}
+	}
+	if o.dropShadow {
+		bad := fmt.Errorf("-shadow-offset %q should be x,y such as 3,3", o.shadowOffset)
+		xy := strings.Split(o.shadowOffset, ",")
+		if len(xy) != 2 {
+			return bad
+		}
+		x, errX := strconv.Atoi(xy[0])
+		y, errY := strconv.Atoi(xy[1])
+		if errX != nil || errY != nil {
+			return bad
+		}
+		if o.shadowBlur <= 0 {
+			return errors.New("-shadow-blur must be more than 0")
+		}
+		o.shadow = &jigsaw.Shadow{Offset: image.Pt(x, y), Blur: o.shadowBlur}
+	}
+	return nil
+}
+
+// listFlag collects every use of a flag that can be repeated
+type listFlag []string
+
+func (l *listFlag) String() string {
+	return strings.Join(*l, " ")
+}
+
+func (l *listFlag) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+func parseAspect(s string) (float64, error) {
+	bad := fmt.Errorf("-aspect %q should be a ratio such as 4:3 or 1.5", s)
+	parts := strings.Split(s, ":")
+	if len(parts) > 2 {
+		return 0, bad
+	}
+	aspect, err := strconv.ParseFloat(parts[0], 64)
+	if err != nil || aspect <= 0 {
+		return 0, bad
+	}
+	if len(parts) == 2 {
+		height, err := strconv.ParseFloat(parts[1], 64)
+		if err != nil || height <= 0 {
+			return 0, bad
+		}
+		aspect /= height
+	}
+	return aspect, nil
+}
+
+func parseColour(flag, s string) (color.Color, error) {
+	hex := strings.TrimPrefix(s, "#")
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil || len(hex) != 6 {
+		return nil, fmt.Errorf("%s %q should be a colour such as #ffffff", flag, s)
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 0xff}, nil
+}
+
+func (o cutOptions) cutter() jigsaw.JigsawPieceCutter {
+	return jigsaw.JigsawPieceCutter{TabSize: o.tab, JointStyle: o.joints}
+}
+
+func (o cutOptions) options() jigsaw.CutOptions {
+	opts := jigsaw.CutOptions{Pieces: o.pieces, Rows: o.rows, Seed: o.seed, Rotate: o.rotate, Fit: o.fit, Cutter: o.cutter(), Format: o.format, TabJitter: o.jitter, EdgeStyle: o.edges, WaveAmplitude: o.wave, Whimsies: o.whimsies, Saliency: o.saliency, Mode: o.mode, Back: o.back, Stroke: o.stroke, Shadow: o.shadow}
+	if o.bevel {
+		opts.Bevel = &jigsaw.Bevel{Angle: o.bevelAngle, Depth: o.bevelDepth}
+	}
+	if o.tabMax > 0 {
+		opts.MinTabSize, opts.MaxTabSize = o.tab, o.tabMax
+	}
+	switch o.layout {
+	case layoutVoronoi:
+		opts.Tiling = jigsaw.VoronoiTiling{Relax: o.relax}
+	case layoutHex:
+		opts.Tiling = jigsaw.HexTiling{}
+	case layoutTriangle:
+		opts.Tiling = jigsaw.TriangleTiling{}
+	}
+	return opts
+}
+
+func cut(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("cut", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	in := flags.String("in", "", "image to cut, jpeg, png, gif, bmp or tiff")
+	opts := &cutOptions{}
+	opts.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() > 0 {
+		return usageError(stderr, "cut", fmt.Errorf("unexpected argument %q", flags.Arg(0)))
+	}
+	if *in == "" {
+		return usageError(stderr, "cut", errors.New("-in is required"))
+	}
+	if err := opts.validate(); err != nil {
+		return usageError(stderr, "cut", err)
+	}
+	if err := opts.load(); err != nil {
+		return usageError(stderr, "cut", err)
+	}
+	jig, err := jigsaw.CutFile(*in, opts.out, opts.options())
+	if err != nil {
+		return err
+	}
+	if opts.layout == layoutGrid {
+		fmt.Fprintf(stdout, "cut %s into %d pieces (%d rows x %d cols) with seed %d\n", *in, len(jig.Pieces), jig.Rows, jig.Cols, jig.Seed)
+	} else {
+		fmt.Fprintf(stdout, "cut %s into %d %s pieces with seed %d\n", *in, len(jig.Pieces), opts.layout, jig.Seed)
+	}
+	fmt.Fprintf(stdout, "wrote %s to %s\n", opts.format, opts.out)
+	return nil
+}
+
+// batchReport is the name of the report batch writes into its output directory
+const batchReport = "report.json"
+
+func batch(args []string, stdout, stderr io.Writer) error {
+	flags := flag.NewFlagSet("batch", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	in := flags.String("in", "", "directory of images to cut")
+	workers := flags.Int("workers", 0, "number of images to cut at once, 0 uses one per cpu")
+	opts := &cutOptions{}
+	opts.register(flags)
+	if err := flags.Parse(args); err != nil {
+		return errUsage
+	}
+	if flags.NArg() > 0 {
+		return usageError(stderr, "batch", fmt.Errorf("unexpected argument %q", flags.Arg(0)))
+	}
+	if *in == "" {
+		return usageError(stderr, "batch", errors.New("-in is required"))
+	}
+	if *workers < 0 {
+		return usageError(stderr, "batch", errors.New("-workers must not be negative"))
+	}
+	if err := opts.validate(); err != nil {
+		return usageError(stderr, "batch", err)
+	}
+	if err := opts.load(); err != nil {
+		return usageError(stderr, "batch", err)
+	}
+	report, err := jigsaw.CutBatch(*in, opts.out, opts.options(), *workers)
+	if err != nil {
+		return err
+	}
+	for _, r := range report.Results {
+		if r.Error != "" {
+			fmt.Fprintf(stdout, "FAIL %s: %s\n", r.Input, r.Error)
+		} else {
+			fmt.Fprintf(stdout, "ok   %s -> %s (%d pieces in %s)\n", r.Input, r.Output, r.Pieces, r.Duration)
+		}
+	}
+	if err := os.MkdirAll(opts.out, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	reportPath := filepath.Join(opts.out, batchReport)
+	if err := ioutil.WriteFile(reportPath, data, 0644); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "cut %d of %d images with seed %d, report written to %s\n", report.Succeeded, len(report.Results), opts.seed, reportPath)
+	if report.Failed > 0 {
+		return fmt.Errorf("%d images could not be cut", report.Failed)
+	}
+	return nil
+}
+
+func usageError(stderr io.Writer, command string, err error) error {
+	fmt.Fprintf(stderr, "jigsaw %s: %s\n", command, err)
+	return errUsage
+}
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+// writeImage writes a png of the given size into dir and returns its path
+func writeImage(t *testing.T, dir string, name string, w, h int) string {
+	path := filepath.Join(dir, name)
+	f, err := os.Create(path)
+	assert.NoError(t, err)
+	defer f.Close()
+	assert.NoError(t, png.Encode(f, image.NewRGBA(image.Rect(0, 0, w, h))))
+	return path
+}
+
+func TestCut(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cut")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	in := writeImage(t, dir, "in.png", 120, 90)
+	out := filepath.Join(dir, "puzzle")
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"cut", "-in", in, "-rows", "3", "-cols", "4", "-seed", "7", "-joints", "square", "-tab", "8", "-tab-max", "15", "-jitter", "0.2", "-edges", "wavy", "-aspect", "16:9", "-pad", "-background", "#336699", "-format", "zip", "-out", out}, stdout, stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "into 12 pieces (3 rows x 4 cols) with seed 7")
+	_, err = os.Stat(filepath.Join(out, "puzzle.zip"))
+	assert.NoError(t, err, "expected the zip to be written")
+
+	stdout.Reset()
+	code = run([]string{"cut", "-in", in, "-pieces", "9", "-layout", "voronoi", "-relax", "3", "-seed", "7", "-out", out}, stdout, stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "into 9 voronoi pieces with seed 7")
+
+	stdout.Reset()
+	code = run([]string{"cut", "-in", in, "-pieces", "12", "-layout", "hex", "-format", "svg", "-out", out}, stdout, stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "hex pieces")
+
+	heart := filepath.Join(dir, "heart.svg")
+	assert.NoError(t, ioutil.WriteFile(heart, []byte(`<svg width="20" height="20"><path d="M10 4 C 14 -2, 24 6, 10 18 C -4 6, 6 -2, 10 4 Z"/></svg>`), 0644))
+	stdout.Reset()
+	code = run([]string{"cut", "-in", in, "-pieces", "9", "-whimsy", heart + "@50,35", "-out", out}, stdout, stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "into 10 pieces")
+
+	stdout.Reset()
+	code = run([]string{"cut", "-in", in, "-pieces", "12", "-saliency", "-protect", heart, "-shift", "0.3", "-out", out}, stdout, stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "into 12 pieces")
+
+	stdout.Reset()
+	code = run([]string{"cut", "-in", in, "-rows", "3", "-cols", "4", "-mode", "edge", "-out", out}, stdout, stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "into 10 pieces (3 rows x 4 cols)")
+
+	back := writeImage(t, dir, "back.png", 120, 90)
+	stdout.Reset()
+	code = run([]string{"cut", "-in", in, "-pieces", "9", "-back", back, "-rotate", "-bevel", "-bevel-angle", "45", "-bevel-depth", "3", "-stroke", "#202020", "-stroke-width", "2", "-shadow", "-shadow-offset", "2,-1", "-shadow-blur", "1.5", "-out", filepath.Join(dir, "double")}, stdout, stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	_, err = os.Stat(filepath.Join(dir, "double", "piece1_back.png"))
+	assert.NoError(t, err, "expected the backs to be written")
+}
+
+func TestCutBadInput(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cut")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	in := writeImage(t, dir, "in.png", 40, 40)
+	notImage := filepath.Join(dir, "notes.txt")
+	assert.NoError(t, ioutil.WriteFile(notImage, []byte("not an image"), 0644))
+
+	for _, c := range []struct {
+		args    []string
+		code    int
+		message string
+	}{
+		{[]string{}, 2, "usage"},
+		{[]string{"paste"}, 2, "unknown command"},
+		{[]string{"cut", "-pieces", "4"}, 2, "-in is required"},
+		{[]string{"cut", "-in", in}, 2, "-pieces"},
+		{[]string{"cut", "-in", in, "-pieces", "6", "-rows", "4"}, 2, "cannot be split into 4 rows"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-joints", "zigzag"}, 2, "unknown joint style"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-tab", "60"}, 2, "tab size"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-format", "pdf"}, 2, "unknown output format"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-aspect", "wide"}, 2, "-aspect"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-tab", "12", "-tab-max", "8"}, 2, "-tab-max"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-jitter", "0.9"}, 2, "-jitter"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-edges", "jagged"}, 2, "unknown edge style"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-edges", "wavy", "-wave", "50"}, 2, "-wave"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-pad", "-background", "red"}, 2, "-background"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-layout", "spiral"}, 2, "unknown layout"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-whimsy", "heart.svg"}, 2, "-whimsy"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-whimsy", filepath.Join(dir, "heart.svg") + "@1,1"}, 2, "heart.svg"},
+		{[]string{"cut", "-in", in, "-rows", "2", "-cols", "2", "-layout", "voronoi"}, 2, "can not have -rows"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-layout", "voronoi", "-edges", "wavy"}, 2, "only be cut on a grid"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-saliency", "-shift", "0.5"}, 2, "-shift"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-saliency", "-layout", "hex"}, 2, "lines of a grid"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-protect", filepath.Join(dir, "mask.png")}, 2, "mask.png"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-mode", "middle"}, 2, "unknown mode"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-back", filepath.Join(dir, "back.png")}, 2, "back.png"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-bevel", "-bevel-depth", "0"}, 2, "-bevel-depth"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-stroke", "red"}, 2, "-stroke"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-shadow", "-shadow-offset", "3"}, 2, "-shadow-offset"},
+		{[]string{"cut", "-in", in, "-pieces", "4", "-back", writeImage(t, dir, "small.png", 20, 20), "-out", dir}, 1, "same size"},
+		{[]string{"cut", "-in", filepath.Join(dir, "missing.png"), "-pieces", "4"}, 1, "missing.png"},
+		{[]string{"cut", "-in", notImage, "-pieces", "4"}, 1, "could not decode"},
+		{[]string{"cut", "-in", in, "-pieces", "10000", "-out", dir}, 1, "too small"},
+	} {
+		stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+		assert.Equal(t, c.code, run(c.args, stdout, stderr), "unexpected exit code for %v", c.args)
+		assert.Contains(t, stderr.String(), c.message, "unexpected message for %v", c.args)
+	}
+}
+
+func TestValidateReadsNoFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "validate")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	missing := filepath.Join(dir, "missing.png")
+	for _, args := range [][]string{
+		{"-pieces", "4", "-whimsy", missing + "@1,1"},
+		{"-pieces", "4", "-protect", missing},
+		{"-pieces", "4", "-back", missing},
+	} {
+		flags := flag.NewFlagSet("cut", flag.ContinueOnError)
+		opts := &cutOptions{}
+		opts.register(flags)
+		assert.NoError(t, flags.Parse(args))
+		assert.NoError(t, opts.validate(), "expected %v to be valid before any file is read", args)
+		err := opts.load()
+		if assert.Error(t, err, "expected loading %v to fail", args) {
+			assert.Contains(t, err.Error(), "missing.png")
+		}
+	}
+
+	//-bevel-depth only matters with -bevel
+	in := writeImage(t, dir, "in.png", 40, 40)
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	assert.Equal(t, 0, run([]string{"cut", "-in", in, "-pieces", "4", "-bevel-depth", "0", "-out", filepath.Join(dir, "out")}, stdout, stderr), stderr.String())
+}
+
+func TestBatch(t *testing.T) {
+	dir, err := ioutil.TempDir("", "batch")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	in, out := filepath.Join(dir, "in"), filepath.Join(dir, "out")
+	assert.NoError(t, os.Mkdir(in, 0755))
+	writeImage(t, in, "one.png", 60, 60)
+	writeImage(t, in, "two.png", 90, 60)
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(in, "bad.gif"), []byte("not a gif"), 0644))
+
+	stdout, stderr := &bytes.Buffer{}, &bytes.Buffer{}
+	code := run([]string{"batch", "-in", in, "-pieces", "4", "-format", "svg", "-out", out}, stdout, stderr)
+	assert.Equal(t, 1, code, "expected a failed image to fail the batch")
+	assert.Contains(t, stdout.String(), "cut 2 of 3 images")
+	assert.Contains(t, stderr.String(), "1 images could not be cut")
+	for _, name := range []string{"one/puzzle.svg", "two/puzzle.svg", "report.json"} {
+		_, err := os.Stat(filepath.Join(out, name))
+		assert.NoError(t, err, "expected %s to be written", name)
+	}
+
+	assert.Equal(t, 2, run([]string{"batch", "-pieces", "4"}, stdout, stderr))
+}
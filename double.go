@@ -0,0 +1,32 @@
+package jigsaw
+
+import (
+	"errors"
+	"image"
+)
+
+var ErrBackSize = errors.New("the back image must be the same size as the front")
+
+func (jb *JigsawBuilder) validateBack() error {
+	if jb.Back != nil && jb.Back.Bounds().Size() != jb.baseImage.Bounds().Size() {
+		return ErrBackSize
+	}
+	return nil
+}
+
+// backPiece cuts the piece out of the back of the board. Turning the board over puts its left on the right,
+// so the piece's part of the back is the mirror of where it is on the front, and turning the piece over
+// mirrors it again, which is how it is kept so it is the right way round when turned over
+func backPiece(back image.Image, board image.Rectangle, p *Piece) image.Image {
+	img := image.NewNRGBA(image.Rect(0, 0, p.Bounds.Dx(), p.Bounds.Dy()))
+	min := back.Bounds().Min
+	for y := p.Bounds.Min.Y; y < p.Bounds.Max.Y; y++ {
+		for x := p.Bounds.Min.X; x < p.Bounds.Max.X; x++ {
+			if !inPiece(p, x, y) {
+				continue
+			}
+			img.Set(p.Bounds.Max.X-1-x, y-p.Bounds.Min.Y, back.At(min.X+board.Max.X-1-x, min.Y+y-board.Min.Y))
+		}
+	}
+	return img
+}
@@ -0,0 +1,118 @@
+package jigsaw_test
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/disintegration/imaging"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+// gradient is an image whose every pixel is a different colour
+func gradient(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, color.NRGBA{uint8(x), uint8(y), 0x80, 0xff})
+		}
+	}
+	return img
+}
+
+func doubleJigsaw(t *testing.T, front image.Image, rotate bool, cutter jigsaw.JigsawPieceCutter) jigsaw.Jigsaw {
+	return buildJigsaw(t, front, 16, cutter, func(b *jigsaw.JigsawBuilder) {
+		b.Back = gradient(160, 160)
+		b.Seed = 4
+		b.Rotate = rotate
+	})
+}
+
+func TestBackIsMirrored(t *testing.T) {
+	flat := jigsaw.JigsawPieceCutter{JointStyle: jigsaw.JOINT_FLAT}
+	jig := doubleJigsaw(t, filled(160, 160, color.White), false, flat)
+	back := gradient(160, 160)
+	//turned over the top left piece is at the top right of the back, the right way round
+	p := jig.Pieces[0]
+	assert.Equal(t, p.Image.Bounds().Size(), p.Back.Bounds().Size())
+	for _, at := range []image.Point{{0, 0}, {39, 0}, {5, 30}} {
+		assert.Equal(t, back.At(120+at.X, at.Y), p.Back.At(at.X, at.Y), "unexpected back at %v", at)
+	}
+	assert.Equal(t, color.NRGBA{0xff, 0xff, 0xff, 0xff}, p.Image.At(0, 0), "expected the front to be the front image")
+}
+
+func TestBackFollowsTheShape(t *testing.T) {
+	//a see through front still has backs the shape of its pieces
+	jig := doubleJigsaw(t, filled(160, 160, color.Transparent), false, jigsaw.JigsawPieceCutter{})
+	full := doubleJigsaw(t, filled(160, 160, color.White), false, jigsaw.JigsawPieceCutter{})
+	for i, p := range jig.Pieces {
+		b := p.Back.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				_, _, _, back := p.Back.At(x, y).RGBA()
+				_, _, _, front := full.Pieces[i].Image.At(b.Max.X-1-x, y).RGBA()
+				if (back == 0) != (front == 0) {
+					t.Fatalf("expected the back of piece %d at %d,%d to be the mirror of its front", p.Index, x, y)
+				}
+			}
+		}
+	}
+}
+
+func TestBackTurnsTheOtherWay(t *testing.T) {
+	flat := jigsaw.JigsawPieceCutter{JointStyle: jigsaw.JOINT_FLAT}
+	still, turned := doubleJigsaw(t, filled(160, 160, color.White), false, flat), doubleJigsaw(t, filled(160, 160, color.White), true, flat)
+	seen := 0
+	for i, p := range turned.Pieces {
+		//imaging turns counter clockwise, the front turned clockwise
+		var want image.Image
+		switch p.Rotation {
+		case 0:
+			want = still.Pieces[i].Back
+		case 90:
+			want = imaging.Rotate90(still.Pieces[i].Back)
+		case 180:
+			want = imaging.Rotate180(still.Pieces[i].Back)
+		case 270:
+			want = imaging.Rotate270(still.Pieces[i].Back)
+		}
+		if p.Rotation == 90 || p.Rotation == 270 {
+			seen++
+		}
+		assert.Equal(t, imaging.Clone(want), imaging.Clone(p.Back), "unexpected back of piece %d turned %d", p.Index, p.Rotation)
+	}
+	assert.True(t, seen > 0, "expected a piece to be turned a quarter")
+}
+
+func TestBackExported(t *testing.T) {
+	dir, err := ioutil.TempDir("", "double")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	jig := doubleJigsaw(t, filled(160, 160, color.White), false, jigsaw.JigsawPieceCutter{})
+	assert.NoError(t, jigsaw.DirExporter{}.Export(jig, filepath.Join(dir, "dir")))
+	m := readManifest(t, filepath.Join(dir, "dir", jigsaw.MANIFEST_FILE))
+	for _, p := range m.Pieces {
+		assert.Equal(t, p.Name+"_back.png", p.BackPath)
+		_, err := os.Stat(filepath.Join(dir, "dir", p.BackPath))
+		assert.NoError(t, err, "expected the back of piece %d to be written", p.Index)
+	}
+
+	assert.NoError(t, jigsaw.AtlasExporter{}.Export(jig, filepath.Join(dir, "atlas")))
+	m = readManifest(t, filepath.Join(dir, "atlas", jigsaw.MANIFEST_FILE))
+	for _, p := range m.Pieces {
+		if assert.NotNil(t, p.BackAtlas, "expected the back of piece %d in the atlas", p.Index) {
+			assert.False(t, p.BackAtlas.Overlaps(*p.Atlas))
+		}
+	}
+}
+
+func TestBackSize(t *testing.T) {
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(filled(160, 160, color.White), 16, jigsaw.JigsawPieceCutter{})
+	builder.Back = gradient(100, 160)
+	_, err := builder.Build()
+	assert.Equal(t, jigsaw.ErrBackSize, err)
+}
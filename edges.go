@@ -0,0 +1,60 @@
+package jigsaw
+
+import (
+	"fmt"
+	"math/rand"
+)
+
+// the styles of the edges between pieces
+const (
+	EDGE_STRAIGHT = "straight"
+	EDGE_WAVY     = "wavy"
+)
+
+// DEFAULT_WAVE_AMPLITUDE and MAX_WAVE_AMPLITUDE are how far a wavy edge moves as a percentage of its length.
+// Past the max the waves of the edges meeting at a corner could cross
+const (
+	DEFAULT_WAVE_AMPLITUDE = 2.5
+	MAX_WAVE_AMPLITUDE     = 5.0
+)
+
+// how many sine terms make up a wavy edge
+const waveTerms = 3
+
+// waveSeed keeps the waves independent of the other choices drawn from the builder's seed
+const waveSeed = 0x5741564553
+
+func (jb *JigsawBuilder) validateEdges() error {
+	switch jb.EdgeStyle {
+	case "", EDGE_STRAIGHT, EDGE_WAVY:
+	default:
+		return fmt.Errorf("unknown edge style %q", jb.EdgeStyle)
+	}
+	if jb.WaveAmplitude < 0 || jb.WaveAmplitude > MAX_WAVE_AMPLITUDE {
+		return fmt.Errorf("wave amplitude should be between 0 and %v percent", MAX_WAVE_AMPLITUDE)
+	}
+	return nil
+}
+
+// waveEdges gives every shared edge a wave drawn from the seed, the same on both sides of the edge. Higher
+// terms get smaller so the edges curve gently rather than zig zag
+func (jb *JigsawBuilder) waveEdges(pieces []*Piece) {
+	if jb.EdgeStyle != EDGE_WAVY {
+		return
+	}
+	amplitude := jb.WaveAmplitude
+	if amplitude == 0 {
+		amplitude = DEFAULT_WAVE_AMPLITUDE
+	}
+	r := rand.New(rand.NewSource(jb.Seed ^ waveSeed))
+	eachEdge(pieces, func(_ *Piece, tab, blank *PieceJoint) {
+		wave := make([]float64, waveTerms)
+		for k := range wave {
+			wave[k] = (r.Float64()*2 - 1) * amplitude / 100 / float64(k+1)
+		}
+		tab.Wave = wave
+		if blank != nil {
+			blank.Wave = append([]float64(nil), wave...)
+		}
+	})
+}
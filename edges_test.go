@@ -0,0 +1,92 @@
+package jigsaw_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func wavyJigsaw(t *testing.T, seed int64, cutter jigsaw.JigsawPieceCutter) jigsaw.Jigsaw {
+	return buildJigsaw(t, filled(240, 180, color.White), 12, cutter, func(b *jigsaw.JigsawBuilder) {
+		b.NumRows = 3
+		b.Seed = seed
+		b.EdgeStyle = jigsaw.EDGE_WAVY
+		b.WaveAmplitude = jigsaw.MAX_WAVE_AMPLITUDE
+		b.MaxTabSize, b.TabJitter = 15, 0.15
+	})
+}
+
+// coverage counts how many pieces cover each pixel of the board
+func coverage(jig jigsaw.Jigsaw) map[image.Point]int {
+	covered := map[image.Point]int{}
+	for _, p := range jig.Pieces {
+		for y := p.Bounds.Min.Y; y < p.Bounds.Max.Y; y++ {
+			for x := p.Bounds.Min.X; x < p.Bounds.Max.X; x++ {
+				if alpha(p, x, y) > 0 {
+					covered[image.Pt(x, y)]++
+				}
+			}
+		}
+	}
+	return covered
+}
+
+func TestWavyEdgesFitTogether(t *testing.T) {
+	for _, cutter := range []jigsaw.JigsawPieceCutter{{}, {JointStyle: jigsaw.JOINT_SQUARE}, {JointStyle: jigsaw.JOINT_FLAT}} {
+		jig := wavyJigsaw(t, 9, cutter)
+		covered := coverage(jig)
+		bad := 0
+		for y := 0; y < 180; y++ {
+			for x := 0; x < 240; x++ {
+				if covered[image.Pt(x, y)] != 1 {
+					bad++
+				}
+			}
+		}
+		assert.Equal(t, 0, bad, "expected every pixel to be in exactly one %q piece", cutter.JointStyle)
+		assert.Len(t, covered, 240*180, "expected nothing outside the board")
+	}
+}
+
+func TestWavyEdgesAreNotStraight(t *testing.T) {
+	jig := wavyJigsaw(t, 4, jigsaw.JigsawPieceCutter{JointStyle: jigsaw.JOINT_FLAT})
+	//a flat piece with straight edges would cover exactly its cell
+	centre := jig.Pieces[5]
+	cell := image.Rect(centre.Col*60, centre.Row*60, (centre.Col+1)*60, (centre.Row+1)*60)
+	outside, missing := 0, 0
+	for y := centre.Bounds.Min.Y; y < centre.Bounds.Max.Y; y++ {
+		for x := centre.Bounds.Min.X; x < centre.Bounds.Max.X; x++ {
+			in := image.Pt(x, y).In(cell)
+			if covered := alpha(centre, x, y) > 0; covered && !in {
+				outside++
+			} else if !covered && in {
+				missing++
+			}
+		}
+	}
+	assert.True(t, outside > 0 && missing > 0, "expected the edges to wave in and out of the cell")
+	for _, j := range centre.Joints {
+		assert.Len(t, j.Wave, 3)
+	}
+
+	straight := jigsaw.NewJigsawBuilderWithPieceCutter(filled(240, 180, color.White), 12, jigsaw.JigsawPieceCutter{})
+	straight.NumRows = 3
+	jig, err := straight.Build()
+	assert.NoError(t, err)
+	for _, j := range jig.Pieces[5].Joints {
+		assert.Nil(t, j.Wave, "did not expect waves by default")
+	}
+}
+
+func TestEdgeStyleValidated(t *testing.T) {
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(filled(100, 100, color.White), 4, jigsaw.JigsawPieceCutter{})
+	builder.EdgeStyle = "zigzag"
+	_, err := builder.Build()
+	assert.Error(t, err)
+	builder.EdgeStyle, builder.WaveAmplitude = jigsaw.EDGE_WAVY, 20
+	_, err = builder.Build()
+	assert.Error(t, err, "expected too large a wave to be rejected")
+}
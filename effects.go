@@ -0,0 +1,150 @@
+package jigsaw
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/disintegration/imaging"
+)
+
+// DEFAULT_STROKE_WIDTH is how many pixels wide a Stroke is and DEFAULT_SHADOW_BLUR how soft a Shadow is
+const (
+	DEFAULT_STROKE_WIDTH = 1
+	DEFAULT_SHADOW_BLUR  = 3.0
+)
+
+// the colour of a shadow that is not given one
+var defaultShadowColour = color.NRGBA{0, 0, 0, 0x80}
+
+// Stroke draws a line of Colour along the edge of every piece, Width pixels in from where it was cut so
+// the pieces keep their size and the lines of two pieces meet along the cut, 0 uses DEFAULT_STROKE_WIDTH
+type Stroke struct {
+	Colour color.Color
+	Width  int
+}
+
+// Shadow drops a shadow of Colour, black and half see through when nil, behind every piece. It is Offset
+// from the piece and blurred by Blur, the sigma of imaging.Blur, 0 using DEFAULT_SHADOW_BLUR. The piece
+// images grow to fit the shadow, by the piece's Padding. Only the front is shadowed, it is the side that
+// lies face up, and it is shadowed once rotated so every piece's shadow falls the same way
+type Shadow struct {
+	Colour color.Color
+	Offset image.Point
+	Blur   float64
+}
+
+// Padding is how many pixels a piece's image reaches past the piece on each side, for its shadow. The
+// piece itself is the image less its padding and is where Bounds says on the board
+type Padding struct {
+	Top    int `json:"top"`
+	Right  int `json:"right"`
+	Bottom int `json:"bottom"`
+	Left   int `json:"left"`
+}
+
+// rotate turns the padding clockwise with its piece
+func (p Padding) rotate(degrees int) Padding {
+	for ; degrees > 0; degrees -= 90 {
+		p = Padding{Top: p.Left, Right: p.Top, Bottom: p.Right, Left: p.Bottom}
+	}
+	return p
+}
+
+func (s *Stroke) validate() error {
+	if s != nil && s.Width < 0 {
+		return errors.New("stroke width must not be negative")
+	}
+	return nil
+}
+
+func (s *Shadow) validate() error {
+	if s != nil && s.Blur < 0 {
+		return errors.New("shadow blur must not be negative")
+	}
+	return nil
+}
+
+// draw strokes the edge of the shape of the image, its pixels that are more than half opaque
+func (s Stroke) draw(img image.Image) image.Image {
+	width := s.Width
+	if width == 0 {
+		width = DEFAULT_STROKE_WIDTH
+	}
+	colour := s.Colour
+	if colour == nil {
+		colour = color.Black
+	}
+	r := img.Bounds()
+	w, h := r.Dx(), r.Dy()
+	inside := func(x, y int) bool {
+		if x < 0 || y < 0 || x >= w || y >= h {
+			return false
+		}
+		_, _, _, a := img.At(r.Min.X+x, r.Min.Y+y).RGBA()
+		return a >= 0x8000
+	}
+	out := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(out, out.Bounds(), img, r.Min, draw.Src)
+	pen := image.NewUniform(colour)
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			if !inside(x, y) {
+				continue
+			}
+			//on the stroke when some pixel within width of it is outside the shape
+			edge := false
+			for dy := -width; dy <= width && !edge; dy++ {
+				for dx := -width; dx <= width && !edge; dx++ {
+					edge = dx*dx+dy*dy <= width*width && !inside(x+dx, y+dy)
+				}
+			}
+			if edge {
+				draw.Draw(out, image.Rect(x, y, x+1, y+1), pen, image.ZP, draw.Over)
+			}
+		}
+	}
+	return out
+}
+
+// pad is how much room the shadow needs around a piece
+func (s Shadow) pad() Padding {
+	blur := s.Blur
+	if blur == 0 {
+		blur = DEFAULT_SHADOW_BLUR
+	}
+	//imaging.Blur reaches three sigma
+	reach := int(math.Ceil(3 * blur))
+	return Padding{
+		Top:    maxInt(0, reach-s.Offset.Y),
+		Right:  maxInt(0, reach+s.Offset.X),
+		Bottom: maxInt(0, reach+s.Offset.Y),
+		Left:   maxInt(0, reach-s.Offset.X),
+	}
+}
+
+// drop puts the image on top of its shadow, in an image grown by pad
+func (s Shadow) drop(img image.Image) image.Image {
+	blur := s.Blur
+	if blur == 0 {
+		blur = DEFAULT_SHADOW_BLUR
+	}
+	colour := s.Colour
+	if colour == nil {
+		colour = defaultShadowColour
+	}
+	pad := s.pad()
+	r := img.Bounds()
+	size := image.Rect(0, 0, r.Dx()+pad.Left+pad.Right, r.Dy()+pad.Top+pad.Bottom)
+	at := image.Pt(pad.Left, pad.Top)
+	//the shape of the image, blurred where the shadow falls
+	mask := image.NewAlpha(size)
+	draw.Draw(mask, r.Sub(r.Min).Add(at).Add(s.Offset), img, r.Min, draw.Src)
+	blurred := imaging.Blur(mask, blur)
+	out := image.NewNRGBA(size)
+	draw.DrawMask(out, size, image.NewUniform(colour), image.ZP, blurred, image.ZP, draw.Src)
+	draw.Draw(out, r.Sub(r.Min).Add(at), img, r.Min, draw.Over)
+	return out
+}
@@ -0,0 +1,98 @@
+package jigsaw_test
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func decoratedJigsaw(t *testing.T, stroke *jigsaw.Stroke, shadow *jigsaw.Shadow, rotate bool) jigsaw.Jigsaw {
+	return buildJigsaw(t, filled(160, 160, color.White), 16, jigsaw.JigsawPieceCutter{JointStyle: jigsaw.JOINT_FLAT}, func(b *jigsaw.JigsawBuilder) {
+		b.Stroke = stroke
+		b.Shadow = shadow
+		b.Rotate = rotate
+		b.Seed = 6
+	})
+}
+
+func TestStrokeOutlinesPieces(t *testing.T) {
+	red := color.NRGBA{0xff, 0, 0, 0xff}
+	jig := decoratedJigsaw(t, &jigsaw.Stroke{Colour: red, Width: 2}, nil, false)
+	img := jig.Pieces[5].Image
+	assert.Equal(t, image.Rect(0, 0, 40, 40), img.Bounds(), "expected the piece to keep its size")
+	for _, at := range []image.Point{{0, 20}, {1, 20}, {20, 39}, {38, 38}} {
+		assert.Equal(t, red, img.At(at.X, at.Y), "expected %v to be on the stroke", at)
+	}
+	for _, at := range []image.Point{{2, 20}, {20, 20}, {2, 2}} {
+		assert.Equal(t, color.NRGBA{0xff, 0xff, 0xff, 0xff}, img.At(at.X, at.Y), "expected %v to be off the stroke", at)
+	}
+	assert.Equal(t, jigsaw.Padding{}, jig.Pieces[5].Padding, "expected no padding without a shadow")
+
+	img = decoratedJigsaw(t, &jigsaw.Stroke{}, nil, false).Pieces[5].Image
+	assert.Equal(t, color.NRGBA{0, 0, 0, 0xff}, img.At(0, 20), "expected a black stroke by default")
+	assert.Equal(t, color.NRGBA{0xff, 0xff, 0xff, 0xff}, img.At(1, 20), "expected the stroke to be one pixel by default")
+}
+
+func TestShadowPadsPieces(t *testing.T) {
+	jig := decoratedJigsaw(t, nil, &jigsaw.Shadow{Offset: image.Pt(4, 4), Blur: 2}, false)
+	p := jig.Pieces[5]
+	assert.Equal(t, jigsaw.Padding{Top: 2, Right: 10, Bottom: 10, Left: 2}, p.Padding)
+	assert.Equal(t, image.Rect(40, 40, 80, 80), p.Bounds, "expected the piece to stay where it is on the board")
+	assert.Equal(t, image.Rect(0, 0, 52, 52), p.Image.Bounds())
+	assert.Equal(t, color.NRGBA{0xff, 0xff, 0xff, 0xff}, p.Image.At(2, 2), "expected the piece inside its padding")
+	_, _, _, a := p.Image.At(46, 46).RGBA()
+	assert.True(t, a > 0, "expected a shadow below and right of the piece")
+	_, _, _, a = p.Image.At(0, 0).RGBA()
+	assert.Equal(t, uint32(0), a, "expected no shadow above and left of the piece")
+
+	m := jig.Manifest()
+	if assert.NotNil(t, m.Pieces[5].Padding) {
+		assert.Equal(t, p.Padding, *m.Pieces[5].Padding)
+	}
+	assert.Equal(t, p.Padding, m.Jigsaw().Pieces[5].Padding)
+	assert.Nil(t, exportedJigsaw(t).Manifest().Pieces[0].Padding, "expected no padding in the manifest without a shadow")
+
+	dir, err := ioutil.TempDir("", "shadow")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	assert.NoError(t, jigsaw.SVGExporter{}.Export(jig, dir))
+	svg, err := ioutil.ReadFile(filepath.Join(dir, jigsaw.SVG_FILE))
+	assert.NoError(t, err)
+	assert.True(t, strings.Contains(string(svg), fmt.Sprintf(`id="%s" x="38" y="38" width="52" height="52"`, p.Name)), "expected the svg to place the piece less its padding")
+}
+
+func TestShadowFallsTheSameWayOnRotatedPieces(t *testing.T) {
+	jig := decoratedJigsaw(t, nil, &jigsaw.Shadow{Offset: image.Pt(3, 0), Blur: 1}, true)
+	turned := 0
+	for _, p := range jig.Pieces {
+		if p.Rotation != 0 {
+			turned++
+		}
+		//the shadow falls to the right of every piece whichever way it is turned
+		assert.Equal(t, jigsaw.Padding{Top: 3, Right: 6, Bottom: 3, Left: 0}, p.Padding, "unexpected padding of piece %d turned %d", p.Index, p.Rotation)
+		assert.Equal(t, image.Rect(0, 0, 46, 46), p.Image.Bounds())
+		_, _, _, a := p.Image.At(42, 23).RGBA()
+		assert.True(t, a > 0, "expected piece %d turned %d to cast a shadow to its right", p.Index, p.Rotation)
+	}
+	assert.True(t, turned > 0, "expected some pieces to be turned")
+}
+
+func TestEffectsValidated(t *testing.T) {
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(filled(100, 100, color.White), 4, jigsaw.JigsawPieceCutter{})
+	builder.Stroke = &jigsaw.Stroke{Width: -1}
+	_, err := builder.Build()
+	assert.Error(t, err)
+
+	builder = jigsaw.NewJigsawBuilderWithPieceCutter(filled(100, 100, color.White), 4, jigsaw.JigsawPieceCutter{})
+	builder.Shadow = &jigsaw.Shadow{Blur: -1}
+	_, err = builder.Build()
+	assert.Error(t, err)
+}
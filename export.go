@@ -0,0 +1,233 @@
+package jigsaw
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// the formats a cut jigsaw can be exported in
+const (
+	FORMAT_DIR   = "dir"
+	FORMAT_ZIP   = "zip"
+	FORMAT_ATLAS = "atlas"
+	FORMAT_SVG   = "svg"
+)
+
+// the names of the files the exporters write into their output directory
+const (
+	MANIFEST_FILE = "manifest.json"
+	ZIP_FILE      = "puzzle.zip"
+	ATLAS_FILE    = "atlas.png"
+	SVG_FILE      = "puzzle.svg"
+)
+
+// Exporter writes a cut jigsaw into a directory, creating it if needed
+type Exporter interface {
+	Export(jig Jigsaw, dir string) error
+}
+
+// NewExporter returns the exporter for one of the FORMAT_ names
+func NewExporter(format string) (Exporter, error) {
+	switch format {
+	case FORMAT_DIR:
+		return DirExporter{}, nil
+	case FORMAT_ZIP:
+		return ZipExporter{}, nil
+	case FORMAT_ATLAS:
+		return AtlasExporter{}, nil
+	case FORMAT_SVG:
+		return SVGExporter{}, nil
+	}
+	return nil, fmt.Errorf("unknown output format %q, expected one of dir, zip, atlas or svg", format)
+}
+
+// DirExporter writes every piece as a png named after the piece, and the back of a double sided piece
+// with _back after its name, along with a manifest.json whose paths are relative to the directory
+type DirExporter struct{}
+
+func (DirExporter) Export(jig Jigsaw, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return writePieces(jig, func(name string, data []byte) error {
+		return ioutil.WriteFile(filepath.Join(dir, name), data, 0644)
+	})
+}
+
+// ZipExporter writes the same files as DirExporter into a single puzzle.zip
+type ZipExporter struct{}
+
+func (ZipExporter) Export(jig Jigsaw, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(filepath.Join(dir, ZIP_FILE))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	err = writePieces(jig, func(name string, data []byte) error {
+		w, err := zw.Create(name)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// writePieces encodes each piece and then the manifest pointing at them
+func writePieces(jig Jigsaw, write func(name string, data []byte) error) error {
+	manifest := jig.Manifest()
+	for i, p := range jig.Pieces {
+		buf := &bytes.Buffer{}
+		if err := encodePiece(buf, p); err != nil {
+			return err
+		}
+		name := p.Name + ".png"
+		if err := write(name, buf.Bytes()); err != nil {
+			return err
+		}
+		manifest.Pieces[i].Path = name
+		if p.Back == nil {
+			continue
+		}
+		buf.Reset()
+		if err := png.Encode(buf, p.Back); err != nil {
+			return err
+		}
+		name = p.Name + "_back.png"
+		if err := write(name, buf.Bytes()); err != nil {
+			return err
+		}
+		manifest.Pieces[i].BackPath = name
+	}
+	return writeManifest(manifest, write)
+}
+
+// AtlasExporter packs every piece into a single atlas.png in a grid of cells the size of the largest
+// piece, followed by the backs of double sided pieces. The manifest.json gives where each piece is in the
+// atlas
+type AtlasExporter struct{}
+
+func (AtlasExporter) Export(jig Jigsaw, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	cell := image.Point{}
+	cells := len(jig.Pieces)
+	for _, p := range jig.Pieces {
+		if p.Image == nil {
+			return fmt.Errorf("piece %d has no image", p.Index)
+		}
+		size := p.Image.Bounds().Size()
+		cell.X, cell.Y = maxInt(cell.X, size.X), maxInt(cell.Y, size.Y)
+		if p.Back != nil {
+			cells++
+		}
+	}
+	cols := int(math.Ceil(math.Sqrt(float64(cells))))
+	rows := 0
+	if cols > 0 {
+		rows = (cells + cols - 1) / cols
+	}
+	atlas := image.NewNRGBA(image.Rect(0, 0, cols*cell.X, rows*cell.Y))
+	manifest := jig.Manifest()
+	place := func(i int, img image.Image) *image.Rectangle {
+		at := image.Pt(i%cols*cell.X, i/cols*cell.Y)
+		r := image.Rectangle{at, at.Add(img.Bounds().Size())}
+		draw.Draw(atlas, r, img, img.Bounds().Min, draw.Src)
+		return &r
+	}
+	next := len(jig.Pieces)
+	for i, p := range jig.Pieces {
+		manifest.Pieces[i].Path = ATLAS_FILE
+		manifest.Pieces[i].Atlas = place(i, p.Image)
+		if p.Back != nil {
+			manifest.Pieces[i].BackPath = ATLAS_FILE
+			manifest.Pieces[i].BackAtlas = place(next, p.Back)
+			next++
+		}
+	}
+	f, err := os.Create(filepath.Join(dir, ATLAS_FILE))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := png.Encode(f, atlas); err != nil {
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return writeManifest(manifest, func(name string, data []byte) error {
+		return ioutil.WriteFile(filepath.Join(dir, name), data, 0644)
+	})
+}
+
+// SVGExporter writes puzzle.svg, the size of the board with every piece embedded as a png image where
+// it sits when solved
+type SVGExporter struct{}
+
+func (SVGExporter) Export(jig Jigsaw, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	buf := &bytes.Buffer{}
+	size := jig.Bounds.Size()
+	fmt.Fprintf(buf, `<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" width="%d" height="%d" viewBox="%d %d %d %d">`+"\n",
+		size.X, size.Y, jig.Bounds.Min.X, jig.Bounds.Min.Y, size.X, size.Y)
+	for _, p := range jig.Pieces {
+		if p.Image == nil {
+			return fmt.Errorf("piece %d has no image", p.Index)
+		}
+		img := &bytes.Buffer{}
+		if err := encodePiece(img, p); err != nil {
+			return err
+		}
+		//rotated pieces keep their place on the board, centred on where they belong. Padding is left out
+		//of the centre so a shadow does not move its piece
+		psize := p.Image.Bounds().Size()
+		centre := p.Bounds.Min.Add(p.Bounds.Max).Div(2)
+		piece := image.Rect(p.Padding.Left, p.Padding.Top, psize.X-p.Padding.Right, psize.Y-p.Padding.Bottom)
+		at := centre.Sub(piece.Min.Add(piece.Max).Div(2))
+		fmt.Fprintf(buf, `  <image id="%s" x="%d" y="%d" width="%d" height="%d" xlink:href="data:image/png;base64,%s"/>`+"\n",
+			p.Name, at.X, at.Y, psize.X, psize.Y, base64.StdEncoding.EncodeToString(img.Bytes()))
+	}
+	buf.WriteString("</svg>\n")
+	return ioutil.WriteFile(filepath.Join(dir, SVG_FILE), buf.Bytes(), 0644)
+}
+
+func encodePiece(w io.Writer, p *Piece) error {
+	if p.Image == nil {
+		return fmt.Errorf("piece %d has no image", p.Index)
+	}
+	return png.Encode(w, p.Image)
+}
+
+func writeManifest(manifest Manifest, write func(name string, data []byte) error) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	return write(MANIFEST_FILE, data)
+}
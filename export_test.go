@@ -0,0 +1,79 @@
+package jigsaw_test
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"image"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func exportedJigsaw(t *testing.T) jigsaw.Jigsaw {
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(image.NewRGBA(image.Rect(0, 0, 120, 80)), 6, jigsaw.JigsawPieceCutter{})
+	builder.NumRows = 2
+	jig, err := builder.Build()
+	assert.NoError(t, err, "did not expect an error building jigsaw")
+	return jig
+}
+
+func readManifest(t *testing.T, path string) jigsaw.Manifest {
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err, "expected a manifest")
+	m := jigsaw.Manifest{}
+	assert.NoError(t, json.Unmarshal(data, &m))
+	return m
+}
+
+func TestExportFormats(t *testing.T) {
+	jig := exportedJigsaw(t)
+	dir, err := ioutil.TempDir("", "export")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	for _, format := range []string{jigsaw.FORMAT_DIR, jigsaw.FORMAT_ZIP, jigsaw.FORMAT_ATLAS, jigsaw.FORMAT_SVG} {
+		exporter, err := jigsaw.NewExporter(format)
+		assert.NoError(t, err, "expected an exporter for %s", format)
+		assert.NoError(t, exporter.Export(jig, filepath.Join(dir, format)), "did not expect an error exporting %s", format)
+	}
+
+	m := readManifest(t, filepath.Join(dir, "dir", jigsaw.MANIFEST_FILE))
+	assert.Len(t, m.Pieces, 6)
+	for _, p := range m.Pieces {
+		_, err := os.Stat(filepath.Join(dir, "dir", p.Path))
+		assert.NoError(t, err, "expected piece %d to be written", p.Index)
+	}
+
+	zr, err := zip.OpenReader(filepath.Join(dir, "zip", jigsaw.ZIP_FILE))
+	assert.NoError(t, err, "expected a zip")
+	assert.Len(t, zr.File, 7)
+	zr.Close()
+
+	m = readManifest(t, filepath.Join(dir, "atlas", jigsaw.MANIFEST_FILE))
+	f, err := os.Open(filepath.Join(dir, "atlas", jigsaw.ATLAS_FILE))
+	assert.NoError(t, err, "expected an atlas")
+	atlas, err := png.Decode(f)
+	f.Close()
+	assert.NoError(t, err)
+	for i, p := range m.Pieces {
+		if assert.NotNil(t, p.Atlas, "expected piece %d to be in the atlas", p.Index) {
+			assert.True(t, p.Atlas.In(atlas.Bounds()), "expected piece %d inside the atlas", p.Index)
+			assert.Equal(t, jig.Pieces[i].Image.Bounds().Size(), p.Atlas.Size())
+		}
+	}
+
+	svg, err := ioutil.ReadFile(filepath.Join(dir, "svg", jigsaw.SVG_FILE))
+	assert.NoError(t, err, "expected an svg")
+	assert.Equal(t, 6, strings.Count(string(svg), "<image "))
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	_, err := jigsaw.NewExporter("gif")
+	assert.Error(t, err)
+}
@@ -0,0 +1,91 @@
+package jigsaw
+
+import (
+	"errors"
+	"image"
+	"image/color"
+
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/disintegration/imaging"
+)
+
+// Fit prepares the image before it is cut. The image is scaled down so its longest side is at most
+// MaxSize, then centre cropped, or padded with Background when Pad is set, to the Aspect ratio of width
+// to height. Finally it is cropped, or padded, so it divides evenly into the grid of pieces. Zero values
+// leave that step out and a nil Background pads with transparency
+type Fit struct {
+	MaxSize    int
+	Aspect     float64
+	Pad        bool
+	Background color.Color
+}
+
+// FitTransform records what Fit did to the image. A point p of the original image is at
+// p * Scaled / Original + Offset in the image that was cut, which is Size big
+type FitTransform struct {
+	Original image.Point `json:"original"`
+	Scaled   image.Point `json:"scaled"`
+	Offset   image.Point `json:"offset"`
+	Size     image.Point `json:"size"`
+	Padded   bool        `json:"padded,omitempty"`
+}
+
+var ErrBadFit = errors.New("fit max size and aspect must not be negative")
+
+// Apply fits the image to be cut into rows and cols of pieces
+func (f Fit) Apply(img image.Image, rows, cols int) (image.Image, FitTransform, error) {
+	if f.MaxSize < 0 || f.Aspect < 0 || rows < 1 || cols < 1 {
+		return nil, FitTransform{}, ErrBadFit
+	}
+	size := img.Bounds().Size()
+	t := FitTransform{Original: size, Scaled: size, Padded: f.Pad}
+	out := img
+	if longest := maxInt(size.X, size.Y); f.MaxSize > 0 && longest > f.MaxSize {
+		scaled := image.Pt(maxInt(1, size.X*f.MaxSize/longest), maxInt(1, size.Y*f.MaxSize/longest))
+		out = imaging.Resize(out, scaled.X, scaled.Y, imaging.Lanczos)
+		t.Scaled = scaled
+	}
+	size = t.Scaled
+	if f.Aspect > 0 {
+		target := size
+		wide := float64(size.X)/float64(size.Y) > f.Aspect
+		if wide == f.Pad {
+			target.Y = maxInt(1, int(float64(size.X)/f.Aspect+0.5))
+		} else {
+			target.X = maxInt(1, int(float64(size.Y)*f.Aspect+0.5))
+		}
+		out = f.resizeCanvas(out, target, &t)
+	}
+	size = out.Bounds().Size()
+	target := image.Pt(size.X-size.X%cols, size.Y-size.Y%rows)
+	if f.Pad {
+		target = image.Pt(roundUp(size.X, cols), roundUp(size.Y, rows))
+	}
+	out = f.resizeCanvas(out, target, &t)
+	t.Size = out.Bounds().Size()
+	return out, t, nil
+}
+
+// resizeCanvas crops or pads the image around its centre to the target size without scaling it
+func (f Fit) resizeCanvas(img image.Image, target image.Point, t *FitTransform) image.Image {
+	size := img.Bounds().Size()
+	if target == size {
+		return img
+	}
+	//both imaging functions line the centres up the same way, rounding down
+	t.Offset = t.Offset.Add(target.Div(2).Sub(size.Div(2)))
+	if target.X <= size.X && target.Y <= size.Y {
+		return imaging.CropCenter(img, target.X, target.Y)
+	}
+	background := f.Background
+	if background == nil {
+		background = color.Transparent
+	}
+	return imaging.PasteCenter(imaging.New(target.X, target.Y, background), img)
+}
+
+func roundUp(n, multiple int) int {
+	if n%multiple == 0 {
+		return n
+	}
+	return n + multiple - n%multiple
+}
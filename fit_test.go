@@ -0,0 +1,65 @@
+package jigsaw_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func filled(w, h int, c color.Color) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.ZP, draw.Src)
+	return img
+}
+
+func TestFitCropsToAspect(t *testing.T) {
+	img, transform, err := jigsaw.Fit{MaxSize: 500, Aspect: 1}.Apply(filled(1000, 600, color.White), 4, 4)
+	assert.NoError(t, err, "did not expect an error fitting")
+	assert.Equal(t, image.Pt(300, 300), img.Bounds().Size())
+	assert.Equal(t, jigsaw.FitTransform{Original: image.Pt(1000, 600), Scaled: image.Pt(500, 300), Offset: image.Pt(-100, 0), Size: image.Pt(300, 300)}, transform)
+}
+
+func TestFitPadsToAspectAndGrid(t *testing.T) {
+	red := color.RGBA{255, 0, 0, 255}
+	img, transform, err := jigsaw.Fit{Aspect: 1, Pad: true, Background: red}.Apply(filled(100, 50, color.White), 3, 3)
+	assert.NoError(t, err, "did not expect an error fitting")
+	assert.Equal(t, image.Pt(102, 102), img.Bounds().Size(), "expected padding up to a multiple of the grid")
+	assert.Equal(t, image.Pt(1, 26), transform.Offset)
+	assert.True(t, transform.Padded)
+	r, g, _, _ := img.At(50, 5).RGBA()
+	assert.True(t, r > 0xf000 && g < 0x1000, "expected the background above the image")
+	r, g, _, _ = img.At(50, 50).RGBA()
+	assert.True(t, r > 0xf000 && g > 0xf000, "expected the image in the middle")
+}
+
+func TestFitCropsToGrid(t *testing.T) {
+	img, transform, err := jigsaw.Fit{}.Apply(filled(103, 98, color.White), 4, 5)
+	assert.NoError(t, err, "did not expect an error fitting")
+	assert.Equal(t, image.Pt(100, 96), img.Bounds().Size())
+	assert.Equal(t, image.Pt(-1, -1), transform.Offset)
+
+	_, _, err = jigsaw.Fit{MaxSize: -1}.Apply(filled(10, 10, color.White), 1, 1)
+	assert.Equal(t, jigsaw.ErrBadFit, err)
+}
+
+func TestBuildWithFitRecordsTransform(t *testing.T) {
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(filled(801, 403, color.White), 12, jigsaw.JigsawPieceCutter{})
+	builder.NumRows = 3
+	builder.Fit = &jigsaw.Fit{MaxSize: 400, Aspect: 4.0 / 3}
+	jig, err := builder.Build()
+	assert.NoError(t, err, "did not expect an error building")
+	assert.Equal(t, image.Rect(0, 0, 268, 201), jig.Bounds)
+	m := jig.Manifest()
+	if assert.NotNil(t, m.Fit, "expected the fit to be in the manifest") {
+		assert.Equal(t, image.Pt(801, 403), m.Fit.Original)
+		assert.Equal(t, jig.Bounds.Size(), m.Fit.Size)
+	}
+	for _, p := range jig.Pieces {
+		assert.Equal(t, 67, p.Width)
+		assert.Equal(t, 67, p.Height)
+	}
+}
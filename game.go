@@ -0,0 +1,378 @@
+package jigsaw
+
+import (
+	"errors"
+	"image"
+	"sort"
+	"time"
+)
+
+// SNAP_TOLERANCE is how many pixels away from its correct spot a piece can be dropped and still snap
+const SNAP_TOLERANCE = 10
+
+var (
+	ErrUnknownPiece  = errors.New("no piece with that index in the game")
+	ErrBadRotation   = errors.New("pieces can only be rotated by multiples of 90 degrees")
+	ErrMissingLayout = errors.New("a layout with a placement for every piece is needed to start a game")
+)
+
+// PieceState is where a piece currently is in the play area. Position is the top left of the box around
+// the piece as it is currently turned and Rotation is how far it is turned clockwise from solved, in degrees
+type PieceState struct {
+	Index    int         `json:"index"`
+	Position image.Point `json:"position"`
+	Rotation int         `json:"rotation"`
+}
+
+// Game is a jigsaw being played. Pieces are moved, rotated and dropped, and a dropped piece snaps onto any
+// neighbour it has been dropped close enough to. Pieces that have snapped together form a group that moves
+// and rotates as one
+type Game struct {
+	Jigsaw       Jigsaw
+	Tolerance    int
+	HistoryLimit int
+	players      map[string]*playerStats
+	pieces       map[int]*Piece
+	states       map[int]*PieceState
+	groups       *groups
+	elapsed      time.Duration
+	resumed      time.Time
+	undo         []command
+	redo         []command
+}
+
+// NewGame starts a game of the jigsaw with the pieces where the layout puts them
+func NewGame(jig Jigsaw, layout *Layout) (*Game, error) {
+	if layout == nil {
+		return nil, ErrMissingLayout
+	}
+	g := &Game{
+		Jigsaw:       jig,
+		Tolerance:    SNAP_TOLERANCE,
+		HistoryLimit: DEFAULT_HISTORY_LIMIT,
+		pieces:       make(map[int]*Piece, len(jig.Pieces)),
+		states:       make(map[int]*PieceState, len(jig.Pieces)),
+		players:      make(map[string]*playerStats),
+		resumed:      time.Now(),
+	}
+	indexes := make([]int, 0, len(jig.Pieces))
+	for _, p := range jig.Pieces {
+		placement, ok := layout.Placement(p.Index)
+		if !ok {
+			return nil, ErrMissingLayout
+		}
+		g.pieces[p.Index] = p
+		g.states[p.Index] = &PieceState{Index: p.Index, Position: placement.Position, Rotation: (p.Rotation + placement.Rotation) % 360}
+		indexes = append(indexes, p.Index)
+	}
+	g.groups = newGroups(indexes)
+	return g, nil
+}
+
+// Elapsed is how long the game has been played for, including any time played before it was saved
+func (g *Game) Elapsed() time.Duration {
+	return g.elapsed + time.Since(g.resumed)
+}
+
+// Piece returns the current state of a piece
+func (g *Game) Piece(index int) (PieceState, error) {
+	s, ok := g.states[index]
+	if !ok {
+		return PieceState{}, ErrUnknownPiece
+	}
+	return *s, nil
+}
+
+// Pieces returns the current state of every piece, ordered by Index
+func (g *Game) Pieces() []PieceState {
+	states := make([]PieceState, 0, len(g.states))
+	for _, p := range g.Jigsaw.Pieces {
+		states = append(states, *g.states[p.Index])
+	}
+	sort.Sort(byIndex(states))
+	return states
+}
+
+// Group returns the Index of every piece joined to the piece, including the piece itself
+func (g *Game) Group(index int) ([]int, error) {
+	if _, ok := g.states[index]; !ok {
+		return nil, ErrUnknownPiece
+	}
+	return sortedCopy(g.members(index)), nil
+}
+
+// GroupID identifies the group the piece is in. Two pieces have the same GroupID only while they are
+// joined, the ID of a group can change when it is joined to another group
+func (g *Game) GroupID(index int) (int, error) {
+	if _, ok := g.states[index]; !ok {
+		return 0, ErrUnknownPiece
+	}
+	return g.groups.find(index), nil
+}
+
+// Groups lists every group of pieces, largest first. Groups of the same size are ordered by their
+// lowest piece Index
+func (g *Game) Groups() [][]int {
+	all := make([][]int, 0, g.groups.count())
+	for _, members := range g.groups.members {
+		all = append(all, sortedCopy(members))
+	}
+	sort.Sort(bySize(all))
+	return all
+}
+
+// Move puts the piece's top left at pos, taking any pieces joined to it along
+func (g *Game) Move(index int, pos image.Point) error {
+	return g.move(SOLO_PLAYER, index, pos)
+}
+
+func (g *Game) move(player string, index int, pos image.Point) error {
+	s, ok := g.states[index]
+	if !ok {
+		return ErrUnknownPiece
+	}
+	g.stats(player)
+	if pos == s.Position {
+		return nil
+	}
+	c := command{player: player, before: g.statesOf(g.members(index))}
+	g.shift(g.members(index), pos.Sub(s.Position))
+	c.after = g.statesOf(g.members(index))
+	g.drag(c)
+	return nil
+}
+
+// Rotate turns the piece clockwise about its centre. Pieces joined to it turn with it
+func (g *Game) Rotate(index, degrees int) error {
+	return g.rotate(SOLO_PLAYER, index, degrees)
+}
+
+func (g *Game) rotate(player string, index, degrees int) error {
+	s, ok := g.states[index]
+	if !ok {
+		return ErrUnknownPiece
+	}
+	g.stats(player)
+	degrees = ((degrees % 360) + 360) % 360
+	if degrees%90 != 0 {
+		return ErrBadRotation
+	}
+	if degrees == 0 {
+		return nil
+	}
+	c := command{player: player, before: g.statesOf(g.members(index))}
+	pivot := g.centre(s)
+	for _, m := range g.members(index) {
+		ms := g.states[m]
+		centre := pivot.Add(rotateVector(g.centre(ms).Sub(pivot), degrees))
+		ms.Rotation = (ms.Rotation + degrees) % 360
+		g.setCentre(ms, centre)
+	}
+	c.after = g.statesOf(g.members(index))
+	g.record(c)
+	return nil
+}
+
+// Drop lets go of the piece. If it, or any piece joined to it, is within Tolerance of where it belongs next
+// to a neighbour that is turned the same way, the group snaps into place against the neighbour and the two
+// are joined. Drop reports whether anything snapped
+func (g *Game) Drop(index int) (bool, error) {
+	return g.drop(SOLO_PLAYER, index, nil)
+}
+
+// drop lets go of the piece, never snapping onto a group with a piece that held reports as held by
+// someone else when held is given
+func (g *Game) drop(player string, index int, held func(piece int) bool) (bool, error) {
+	if _, ok := g.states[index]; !ok {
+		return false, ErrUnknownPiece
+	}
+	stats := g.stats(player)
+	stats.Moves++
+	g.endDrag(player)
+	//only the pieces that were let go of can have landed next to a neighbour
+	dropped := sortedCopy(g.members(index))
+	moved := make(map[int]PieceState)
+	c := command{player: player}
+	for {
+		m, n, offset, ok := g.findSnap(dropped, held)
+		if !ok {
+			break
+		}
+		for _, member := range g.members(m) {
+			if _, ok := moved[member]; !ok {
+				moved[member] = *g.states[member]
+			}
+		}
+		g.shift(g.members(m), offset)
+		c.merges = append(c.merges, merge{pieces: sortedCopy(g.members(m)), with: n})
+		g.join(m, n)
+	}
+	if len(c.merges) == 0 {
+		return false, nil
+	}
+	stats.Snaps += len(c.merges)
+	indexes := make([]int, 0, len(moved))
+	for i, before := range moved {
+		c.before = append(c.before, before)
+		indexes = append(indexes, i)
+	}
+	sort.Sort(byIndex(c.before))
+	c.after = g.statesOf(indexes)
+	g.record(c)
+	return true, nil
+}
+
+// Complete is true once every piece has been joined and the puzzle is the right way up
+func (g *Game) Complete() bool {
+	if g.groups.count() > 1 {
+		return false
+	}
+	for _, s := range g.states {
+		if s.Rotation != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// findSnap looks for one of the pieces that is close enough to a neighbour outside its group to snap.
+// It returns the piece, the neighbour and how far the piece's group has to move to line up. Groups with a
+// piece that held reports are not snapped to
+func (g *Game) findSnap(pieces []int, held func(piece int) bool) (int, int, image.Point, bool) {
+	//centres are worked in double size so that pieces with an odd width or height line up exactly
+	tolerance := 2 * g.Tolerance
+	for _, m := range pieces {
+		ms := g.states[m]
+		for _, n := range g.pieces[m].Neighbours() {
+			ns, ok := g.states[n]
+			if !ok || g.joined(m, n) || ns.Rotation != ms.Rotation || g.anyHeld(n, held) {
+				continue
+			}
+			solved := g.solvedCentre(n).Sub(g.solvedCentre(m))
+			want := g.centre(ns).Sub(rotateVector(solved, ms.Rotation))
+			diff := want.Sub(g.centre(ms))
+			if abs(diff.X) <= tolerance && abs(diff.Y) <= tolerance {
+				return m, n, diff.Div(2), true
+			}
+		}
+	}
+	return 0, 0, image.Point{}, false
+}
+
+// anyHeld reports whether held reports any piece of the group
+func (g *Game) anyHeld(index int, held func(piece int) bool) bool {
+	if held == nil {
+		return false
+	}
+	for _, m := range g.members(index) {
+		if held(m) {
+			return true
+		}
+	}
+	return false
+}
+
+// statesOf copies the state of each of the pieces, ordered by Index
+func (g *Game) statesOf(indexes []int) []PieceState {
+	states := make([]PieceState, len(indexes))
+	for i, index := range indexes {
+		states[i] = *g.states[index]
+	}
+	sort.Sort(byIndex(states))
+	return states
+}
+
+func (g *Game) members(index int) []int {
+	return g.groups.group(index)
+}
+
+func (g *Game) joined(a, b int) bool {
+	return g.groups.find(a) == g.groups.find(b)
+}
+
+func (g *Game) join(a, b int) {
+	g.groups.union(a, b)
+}
+
+func (g *Game) shift(members []int, by image.Point) {
+	for _, m := range members {
+		g.states[m].Position = g.states[m].Position.Add(by)
+	}
+}
+
+// size is the size of the box around the piece when turned by rotation degrees
+func (g *Game) size(index, rotation int) image.Point {
+	size := g.pieces[index].Bounds.Size()
+	if rotation == 90 || rotation == 270 {
+		return image.Pt(size.Y, size.X)
+	}
+	return size
+}
+
+// centre is twice the centre of the piece in the play area
+func (g *Game) centre(s *PieceState) image.Point {
+	return s.Position.Mul(2).Add(g.size(s.Index, s.Rotation))
+}
+
+func (g *Game) setCentre(s *PieceState, centre image.Point) {
+	s.Position = centre.Sub(g.size(s.Index, s.Rotation)).Div(2)
+}
+
+// solvedCentre is twice the centre of the piece on the board
+func (g *Game) solvedCentre(index int) image.Point {
+	b := g.pieces[index].Bounds
+	return b.Min.Mul(2).Add(b.Size())
+}
+
+// rotateVector turns v clockwise on screen, where y grows downwards
+func rotateVector(v image.Point, degrees int) image.Point {
+	for i := 0; i < degrees/90; i++ {
+		v = image.Pt(-v.Y, v.X)
+	}
+	return v
+}
+
+func sortedCopy(indexes []int) []int {
+	sorted := make([]int, len(indexes))
+	copy(sorted, indexes)
+	sort.Ints(sorted)
+	return sorted
+}
+
+type byIndex []PieceState
+
+func (s byIndex) Len() int           { return len(s) }
+func (s byIndex) Swap(i, j int)      { s[i], s[j] = s[j], s[i] }
+func (s byIndex) Less(i, j int) bool { return s[i].Index < s[j].Index }
+
+type bySize [][]int
+
+func (s bySize) Len() int      { return len(s) }
+func (s bySize) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s bySize) Less(i, j int) bool {
+	if len(s[i]) != len(s[j]) {
+		return len(s[i]) > len(s[j])
+	}
+	return s[i][0] < s[j][0]
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
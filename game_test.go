@@ -0,0 +1,253 @@
+package jigsaw_test
+
+import (
+	"image"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+// newGame starts a 2x2 game of 100x100 pieces with every piece well away from the others
+func newGame(t *testing.T) *jigsaw.Game {
+	builder := jigsaw.NewJigsawBuilder(image.NewRGBA(image.Rect(0, 0, 200, 200)), 4)
+	builder.NumRows = 2
+	pieces, err := builder.BuildPieces()
+	assert.NoError(t, err, "did not expect an error building pieces")
+	jig := jigsaw.Jigsaw{Rows: 2, Pieces: pieces, Bounds: image.Rect(0, 0, 200, 200)}
+	layout := &jigsaw.Layout{}
+	for i, p := range pieces {
+		layout.Placements = append(layout.Placements, jigsaw.Placement{Index: p.Index, Position: image.Pt(i*1000, 1000)})
+	}
+	game, err := jigsaw.NewGame(jig, layout)
+	assert.NoError(t, err, "did not expect an error starting a game")
+	return game
+}
+
+func position(t *testing.T, g *jigsaw.Game, index int) image.Point {
+	s, err := g.Piece(index)
+	assert.NoError(t, err, "did not expect an error getting piece")
+	return s.Position
+}
+
+func TestGameDropSnapsToNeighbour(t *testing.T) {
+	g := newGame(t)
+	assert.NoError(t, g.Move(1, image.Pt(0, 0)))
+	assert.NoError(t, g.Move(2, image.Pt(104, -7)))
+	snapped, err := g.Drop(2)
+	assert.NoError(t, err, "did not expect an error")
+	assert.True(t, snapped, "expected piece to snap")
+	assert.Equal(t, image.Pt(100, 0), position(t, g, 2))
+	group, _ := g.Group(1)
+	assert.Len(t, group, 2)
+
+	//the joined pieces move together
+	assert.NoError(t, g.Move(1, image.Pt(50, 50)))
+	assert.Equal(t, image.Pt(150, 50), position(t, g, 2))
+	assert.False(t, g.Complete())
+}
+
+func TestGameDropOutsideToleranceDoesNotSnap(t *testing.T) {
+	g := newGame(t)
+	assert.NoError(t, g.Move(1, image.Pt(0, 0)))
+	assert.NoError(t, g.Move(2, image.Pt(130, 0)))
+	snapped, err := g.Drop(2)
+	assert.NoError(t, err, "did not expect an error")
+	assert.False(t, snapped, "did not expect piece to snap")
+	assert.Equal(t, image.Pt(130, 0), position(t, g, 2))
+}
+
+func TestGameRotatedPiecesOnlySnapWhenTurnedTheSameWay(t *testing.T) {
+	g := newGame(t)
+	assert.NoError(t, g.Move(1, image.Pt(0, 0)))
+	assert.NoError(t, g.Rotate(2, 90))
+	assert.NoError(t, g.Move(2, image.Pt(100, 0)))
+	snapped, _ := g.Drop(2)
+	assert.False(t, snapped, "did not expect pieces turned differently to snap")
+
+	//turned clockwise piece 2 belongs below piece 1
+	assert.NoError(t, g.Rotate(1, 90))
+	assert.NoError(t, g.Move(2, image.Pt(2, 98)))
+	snapped, _ = g.Drop(2)
+	assert.True(t, snapped, "expected piece to snap")
+	assert.Equal(t, image.Pt(0, 100), position(t, g, 2))
+}
+
+func TestGameComplete(t *testing.T) {
+	g := newGame(t)
+	assert.NoError(t, g.Rotate(4, 180))
+	for index, pos := range map[int]image.Point{1: {0, 0}, 2: {100, 0}, 3: {0, 100}, 4: {100, 100}} {
+		assert.NoError(t, g.Move(index, pos))
+	}
+	for index := 1; index <= 4; index++ {
+		g.Drop(index)
+	}
+	assert.False(t, g.Complete(), "piece 4 is upside down")
+	assert.NoError(t, g.Rotate(4, 180))
+	snapped, _ := g.Drop(4)
+	assert.True(t, snapped, "expected piece to snap")
+	assert.True(t, g.Complete(), "expected puzzle to be complete")
+}
+
+func TestGameErrors(t *testing.T) {
+	g := newGame(t)
+	assert.Equal(t, jigsaw.ErrUnknownPiece, g.Move(9, image.Pt(0, 0)))
+	assert.Equal(t, jigsaw.ErrBadRotation, g.Rotate(1, 30))
+	_, err := jigsaw.NewGame(g.Jigsaw, nil)
+	assert.Equal(t, jigsaw.ErrMissingLayout, err)
+}
+
+func TestGameGroups(t *testing.T) {
+	g := newGame(t)
+	assert.NoError(t, g.Move(3, image.Pt(0, 0)))
+	assert.NoError(t, g.Move(4, image.Pt(100, 0)))
+	g.Drop(4)
+	assert.Equal(t, [][]int{{3, 4}, {1}, {2}}, g.Groups())
+
+	id3, _ := g.GroupID(3)
+	id4, _ := g.GroupID(4)
+	id1, _ := g.GroupID(1)
+	assert.Equal(t, id3, id4)
+	assert.NotEqual(t, id3, id1)
+}
+
+// largeGame starts a game of rows x cols 10px pieces with every piece spread out away from the board
+func largeGame(t testing.TB, rows, cols int) *jigsaw.Game {
+	bounds := image.Rect(0, 0, cols*10, rows*10)
+	builder := jigsaw.NewJigsawBuilder(image.NewAlpha(bounds), rows*cols)
+	builder.NumRows = rows
+	pieces, err := builder.BuildPieces()
+	if err != nil {
+		t.Fatal(err)
+	}
+	layout := &jigsaw.Layout{}
+	for _, p := range pieces {
+		layout.Placements = append(layout.Placements, jigsaw.Placement{Index: p.Index, Position: p.Bounds.Min.Mul(5).Add(image.Pt(10000, 0))})
+	}
+	game, err := jigsaw.NewGame(jigsaw.Jigsaw{Rows: rows, Pieces: pieces, Bounds: bounds}, layout)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return game
+}
+
+func solve(g *jigsaw.Game) {
+	for _, p := range g.Jigsaw.Pieces {
+		g.Move(p.Index, p.Bounds.Min)
+		g.Drop(p.Index)
+	}
+}
+
+func TestGameGroups5000Pieces(t *testing.T) {
+	g := largeGame(t, 50, 100)
+	assert.Len(t, g.Groups(), 5000)
+	solve(g)
+	assert.Len(t, g.Groups(), 1)
+	assert.True(t, g.Complete(), "expected puzzle to be complete")
+	group, _ := g.Group(5000)
+	assert.Len(t, group, 5000)
+}
+
+func BenchmarkGameSolve5000Pieces(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		g := largeGame(b, 50, 100)
+		b.StartTimer()
+		solve(g)
+	}
+}
+
+func TestGameUndoRedo(t *testing.T) {
+	g := newGame(t)
+	assert.False(t, g.CanUndo())
+	assert.Equal(t, jigsaw.ErrNothingToUndo, g.Undo())
+
+	assert.NoError(t, g.Move(1, image.Pt(0, 0)))
+	assert.NoError(t, g.Move(2, image.Pt(103, 4)))
+	snapped, _ := g.Drop(2)
+	assert.True(t, snapped, "expected piece to snap")
+	assert.NoError(t, g.Rotate(1, 90))
+	joined := g.Pieces()
+
+	//undo the rotation then the snap
+	assert.NoError(t, g.Undo())
+	assert.Equal(t, 0, g.Pieces()[0].Rotation)
+	assert.NoError(t, g.Undo())
+	assert.Equal(t, image.Pt(103, 4), position(t, g, 2))
+	assert.Len(t, g.Groups(), 4, "expected the snap to be undone")
+	assert.NoError(t, g.Move(1, image.Pt(0, 0)), "moving to where it already is does nothing")
+	assert.Equal(t, image.Pt(103, 4), position(t, g, 2), "the pieces should no longer move together")
+	assert.NoError(t, g.Undo())
+	assert.Equal(t, image.Pt(2000, 1000), position(t, g, 3))
+	assert.Equal(t, image.Pt(1000, 1000), position(t, g, 2))
+
+	assert.NoError(t, g.Redo())
+	assert.NoError(t, g.Redo())
+	assert.NoError(t, g.Redo())
+	assert.Equal(t, joined, g.Pieces())
+	assert.Equal(t, [][]int{{1, 2}, {3}, {4}}, g.Groups())
+	assert.Equal(t, jigsaw.ErrNothingToRedo, g.Redo())
+
+	//a new move clears anything that could be redone
+	assert.NoError(t, g.Undo())
+	assert.NoError(t, g.Move(4, image.Pt(5, 5)))
+	assert.False(t, g.CanRedo())
+}
+
+func TestGameHistoryLimit(t *testing.T) {
+	g := newGame(t)
+	g.HistoryLimit = 2
+	for x := 1; x <= 5; x++ {
+		assert.NoError(t, g.Move(1, image.Pt(x, 0)))
+		_, err := g.Drop(1)
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, g.Undo())
+	assert.NoError(t, g.Undo())
+	assert.Equal(t, jigsaw.ErrNothingToUndo, g.Undo())
+	assert.Equal(t, image.Pt(3, 0), position(t, g, 1))
+}
+
+func TestGameDragIsOneMove(t *testing.T) {
+	g := newGame(t)
+	g.HistoryLimit = 2
+	//dragging a piece about moves it many times before it is dropped
+	for x := 1; x <= 5; x++ {
+		assert.NoError(t, g.Move(1, image.Pt(x, 0)))
+	}
+	_, err := g.Drop(1)
+	assert.NoError(t, err)
+	assert.NoError(t, g.Move(1, image.Pt(50, 0)))
+	assert.NoError(t, g.Move(2, image.Pt(500, 0)))
+	assert.NoError(t, g.Undo())
+	assert.NoError(t, g.Undo())
+	assert.Equal(t, image.Pt(5, 0), position(t, g, 1), "expected the move after the drop to be undone on its own")
+	assert.Equal(t, jigsaw.ErrNothingToUndo, g.Undo(), "expected the drag to have left room in the history")
+
+	g = newGame(t)
+	for x := 1; x <= 5; x++ {
+		assert.NoError(t, g.Move(1, image.Pt(x, 0)))
+	}
+	assert.NoError(t, g.Undo())
+	assert.Equal(t, image.Pt(0, 1000), position(t, g, 1), "expected the whole drag to be undone")
+	assert.NoError(t, g.Redo())
+	assert.Equal(t, image.Pt(5, 0), position(t, g, 1))
+}
+
+func TestGameHistoryIsShared(t *testing.T) {
+	g := newGame(t)
+	alice, bob := g.Player("alice"), g.Player("bob")
+	assert.NoError(t, alice.Move(1, image.Pt(1, 0)))
+	assert.NoError(t, bob.Move(2, image.Pt(500, 0)))
+	assert.NoError(t, alice.Move(1, image.Pt(2, 0)))
+	assert.NoError(t, alice.Move(1, image.Pt(3, 0)))
+	//undo takes back whatever anyone did last, and the drags of two players are kept apart
+	assert.NoError(t, g.Undo())
+	assert.Equal(t, image.Pt(1, 0), position(t, g, 1))
+	assert.Equal(t, image.Pt(500, 0), position(t, g, 2))
+	assert.NoError(t, g.Undo())
+	assert.Equal(t, image.Pt(1000, 1000), position(t, g, 2), "expected bob's move to be undone")
+	assert.NoError(t, g.Undo())
+	assert.Equal(t, image.Pt(0, 1000), position(t, g, 1))
+	assert.False(t, g.CanUndo())
+}
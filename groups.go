@@ -0,0 +1,80 @@
+package jigsaw
+
+// groups tracks which pieces have been joined using union-find. Every group is keyed by its root piece
+// which also holds the group's member list, so finding a piece's group is close to constant time and
+// moving a group only touches the pieces in it
+type groups struct {
+	parent  map[int]int
+	members map[int][]int
+}
+
+func newGroups(indexes []int) *groups {
+	g := &groups{parent: make(map[int]int, len(indexes)), members: make(map[int][]int, len(indexes))}
+	for _, i := range indexes {
+		g.parent[i] = i
+		g.members[i] = []int{i}
+	}
+	return g
+}
+
+func (g *groups) find(i int) int {
+	root := i
+	for g.parent[root] != root {
+		root = g.parent[root]
+	}
+	for g.parent[i] != root {
+		g.parent[i], i = root, g.parent[i]
+	}
+	return root
+}
+
+// union joins the groups of a and b, the smaller group is added to the larger one
+func (g *groups) union(a, b int) {
+	ra, rb := g.find(a), g.find(b)
+	if ra == rb {
+		return
+	}
+	if len(g.members[ra]) < len(g.members[rb]) {
+		ra, rb = rb, ra
+	}
+	g.parent[rb] = ra
+	g.members[ra] = append(g.members[ra], g.members[rb]...)
+	delete(g.members, rb)
+}
+
+func (g *groups) group(i int) []int {
+	return g.members[g.find(i)]
+}
+
+func (g *groups) count() int {
+	return len(g.members)
+}
+
+// split takes the pieces out of the group they are in and makes them a group of their own, it is how a
+// join is undone
+func (g *groups) split(pieces []int) {
+	root := g.find(pieces[0])
+	taken := make(map[int]bool, len(pieces))
+	for _, p := range pieces {
+		taken[p] = true
+	}
+	rest := make([]int, 0, len(g.members[root]))
+	for _, m := range g.members[root] {
+		if !taken[m] {
+			rest = append(rest, m)
+		}
+	}
+	delete(g.members, root)
+	g.regroup(append([]int(nil), pieces...))
+	g.regroup(rest)
+}
+
+func (g *groups) regroup(members []int) {
+	if len(members) == 0 {
+		return
+	}
+	for _, m := range members {
+		g.parent[m] = members[0]
+	}
+	g.members[members[0]] = members
+}
@@ -0,0 +1,214 @@
+package jigsaw
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math/rand"
+	"sort"
+)
+
+var ErrNoHint = errors.New("there is no piece left to hint at")
+
+// Hint says where a piece should go. Position and Rotation are where it snaps onto NextTo as NextTo is
+// currently placed
+type Hint struct {
+	Piece    int         `json:"piece"`
+	NextTo   int         `json:"nextTo"`
+	Position image.Point `json:"position"`
+	Rotation int         `json:"rotation"`
+}
+
+// HintStrategy picks which of the possible hints to give. It is only ever given at least one candidate
+type HintStrategy interface {
+	Choose(g *Game, candidates []Hint) Hint
+}
+
+// Hint suggests a piece that has not been joined to anything yet and where to put it to join a group that
+// has already been put together. Before anything has been joined any piece can be hinted at. Once every
+// piece is in a group the hint is where to put a piece to bring its group onto a neighbouring group at
+// least as big. The strategy decides which piece, when nil EdgesFirstHints is used
+func (g *Game) Hint(strategy HintStrategy) (Hint, error) {
+	return g.hint(SOLO_PLAYER, strategy)
+}
+
+func (g *Game) hint(player string, strategy HintStrategy) (Hint, error) {
+	if strategy == nil {
+		strategy = EdgesFirstHints{}
+	}
+	candidates := g.hintCandidates()
+	if len(candidates) == 0 {
+		return Hint{}, ErrNoHint
+	}
+	g.stats(player).Hints++
+	return strategy.Choose(g, candidates), nil
+}
+
+// hintCandidates finds every loose piece next to a joined group, against the largest neighbouring group
+func (g *Game) hintCandidates() []Hint {
+	anyGroup := g.groups.count() == len(g.states)
+	candidates := make([]Hint, 0)
+	for _, p := range g.Jigsaw.Pieces {
+		if len(g.members(p.Index)) > 1 {
+			continue
+		}
+		best, size := 0, 0
+		for _, n := range p.Neighbours() {
+			if _, ok := g.states[n]; !ok {
+				continue
+			}
+			if s := len(g.members(n)); (s > 1 || anyGroup) && s > size {
+				best, size = n, s
+			}
+		}
+		if best == 0 {
+			continue
+		}
+		candidates = append(candidates, g.hintFor(p.Index, best))
+	}
+	if len(candidates) == 0 {
+		return g.joinCandidates()
+	}
+	return candidates
+}
+
+// joinCandidates finds every piece that can bring its group onto a neighbouring group at least as big, for
+// when no piece is left on its own
+func (g *Game) joinCandidates() []Hint {
+	candidates := make([]Hint, 0)
+	for _, p := range g.Jigsaw.Pieces {
+		if _, ok := g.states[p.Index]; !ok {
+			continue
+		}
+		size := len(g.members(p.Index))
+		for _, n := range p.Neighbours() {
+			if _, ok := g.states[n]; !ok || g.joined(p.Index, n) || len(g.members(n)) < size {
+				continue
+			}
+			candidates = append(candidates, g.hintFor(p.Index, n))
+			break
+		}
+	}
+	return candidates
+}
+
+// hintFor works out where index goes to join onto nextTo
+func (g *Game) hintFor(index, nextTo int) Hint {
+	anchor := g.states[nextTo]
+	solved := g.solvedCentre(index).Sub(g.solvedCentre(nextTo))
+	centre := g.centre(anchor).Add(rotateVector(solved, anchor.Rotation))
+	target := &PieceState{Index: index, Rotation: anchor.Rotation}
+	g.setCentre(target, centre)
+	return Hint{Piece: index, NextTo: nextTo, Position: target.Position, Rotation: target.Rotation}
+}
+
+// EdgesFirstHints hints at corner pieces, then edge pieces, then the rest so the frame gets built first
+type EdgesFirstHints struct{}
+
+func (EdgesFirstHints) Choose(g *Game, candidates []Hint) Hint {
+	rank := func(h Hint) int {
+		p := g.pieces[h.Piece]
+		if p.IsCorner {
+			return 0
+		}
+		if p.IsEdge {
+			return 1
+		}
+		return 2
+	}
+	sort.Sort(hintsBy{candidates, func(a, b Hint) bool {
+		if rank(a) != rank(b) {
+			return rank(a) < rank(b)
+		}
+		return a.Piece < b.Piece
+	}})
+	return candidates[0]
+}
+
+// ColourRegionHints hints at the piece whose colour is closest to the piece it joins, so that areas of
+// the same colour get filled in together. Pieces without images are treated as edges first
+type ColourRegionHints struct {
+	colours map[int]color.RGBA
+}
+
+func (c *ColourRegionHints) Choose(g *Game, candidates []Hint) Hint {
+	if c.colours == nil {
+		c.colours = make(map[int]color.RGBA)
+	}
+	best, distance := -1, 0
+	for i, h := range candidates {
+		a, aok := c.colour(g.pieces[h.Piece])
+		b, bok := c.colour(g.pieces[h.NextTo])
+		if !aok || !bok {
+			continue
+		}
+		if d := colourDistance(a, b); best < 0 || d < distance {
+			best, distance = i, d
+		}
+	}
+	if best < 0 {
+		return EdgesFirstHints{}.Choose(g, candidates)
+	}
+	return candidates[best]
+}
+
+func (c *ColourRegionHints) colour(p *Piece) (color.RGBA, bool) {
+	if p.Image == nil {
+		return color.RGBA{}, false
+	}
+	if col, ok := c.colours[p.Index]; ok {
+		return col, true
+	}
+	col := averageColour(p.Image)
+	c.colours[p.Index] = col
+	return col, true
+}
+
+// RandomHints hints at any of the possible pieces
+type RandomHints struct {
+	Rand *rand.Rand
+}
+
+func (r RandomHints) Choose(g *Game, candidates []Hint) Hint {
+	if r.Rand == nil {
+		return candidates[rand.Intn(len(candidates))]
+	}
+	return candidates[r.Rand.Intn(len(candidates))]
+}
+
+// averageColour is the average of the pixels of the image that are not transparent
+func averageColour(img image.Image) color.RGBA {
+	var r, g, b, n uint64
+	bounds := img.Bounds()
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			cr, cg, cb, ca := img.At(x, y).RGBA()
+			if ca == 0 {
+				continue
+			}
+			//undo the premultiplied alpha
+			r += uint64(cr) * 0xffff / uint64(ca)
+			g += uint64(cg) * 0xffff / uint64(ca)
+			b += uint64(cb) * 0xffff / uint64(ca)
+			n++
+		}
+	}
+	if n == 0 {
+		return color.RGBA{}
+	}
+	return color.RGBA{uint8(r / n >> 8), uint8(g / n >> 8), uint8(b / n >> 8), 0xff}
+}
+
+func colourDistance(a, b color.RGBA) int {
+	dr, dg, db := int(a.R)-int(b.R), int(a.G)-int(b.G), int(a.B)-int(b.B)
+	return dr*dr + dg*dg + db*db
+}
+
+type hintsBy struct {
+	hints []Hint
+	less  func(a, b Hint) bool
+}
+
+func (h hintsBy) Len() int           { return len(h.hints) }
+func (h hintsBy) Swap(i, j int)      { h.hints[i], h.hints[j] = h.hints[j], h.hints[i] }
+func (h hintsBy) Less(i, j int) bool { return h.less(h.hints[i], h.hints[j]) }
@@ -0,0 +1,96 @@
+package jigsaw_test
+
+import (
+	"image"
+	"image/color"
+	"math/rand"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func followHint(t *testing.T, g *jigsaw.Game, h jigsaw.Hint) {
+	assert.NoError(t, g.Rotate(h.Piece, h.Rotation))
+	assert.NoError(t, g.Move(h.Piece, h.Position))
+	snapped, err := g.Drop(h.Piece)
+	assert.NoError(t, err)
+	assert.True(t, snapped, "expected piece %d to snap where the hint said", h.Piece)
+}
+
+func TestHintEdgesFirst(t *testing.T) {
+	g := largeGame(t, 3, 3)
+	h, err := g.Hint(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, h.Piece, "expected a corner first")
+	followHint(t, g, h)
+
+	//with pieces 1 and 2 joined the other top corner comes before the edge below 1 and the centre
+	h, err = g.Hint(jigsaw.EdgesFirstHints{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, h.Piece)
+	assert.Equal(t, 2, h.NextTo)
+}
+
+func TestHintFollowsRotatedGroups(t *testing.T) {
+	g := largeGame(t, 3, 3)
+	followHint(t, g, jigsaw.Hint{Piece: 2, NextTo: 1, Position: image.Pt(10010, 0)})
+	assert.NoError(t, g.Rotate(1, 180))
+	h, err := g.Hint(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 180, h.Rotation)
+	followHint(t, g, h)
+}
+
+func TestHintSolvesPuzzle(t *testing.T) {
+	g := largeGame(t, 4, 5)
+	strategy := jigsaw.RandomHints{Rand: rand.New(rand.NewSource(3))}
+	for i := 0; i < 19; i++ {
+		h, err := g.Hint(strategy)
+		assert.NoError(t, err)
+		followHint(t, g, h)
+	}
+	assert.True(t, g.Complete(), "expected following every hint to solve the puzzle")
+	_, err := g.Hint(strategy)
+	assert.Equal(t, jigsaw.ErrNoHint, err)
+}
+
+func TestHintByColourRegion(t *testing.T) {
+	g := largeGame(t, 3, 3)
+	red, blue := image.NewUniform(color.RGBA{200, 0, 0, 255}), image.NewUniform(color.RGBA{0, 0, 200, 255})
+	for _, p := range g.Jigsaw.Pieces {
+		p.Image = solid(blue)
+		if p.Index == 1 || p.Index == 2 || p.Index == 4 {
+			p.Image = solid(red)
+		}
+	}
+	followHint(t, g, jigsaw.Hint{Piece: 2, NextTo: 1, Position: image.Pt(10010, 0)})
+	h, err := g.Hint(&jigsaw.ColourRegionHints{})
+	assert.NoError(t, err)
+	assert.Equal(t, 4, h.Piece, "expected the red piece next to the red corner")
+}
+
+func solid(c image.Image) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for x := 0; x < 10; x++ {
+		for y := 0; y < 10; y++ {
+			img.Set(x, y, c.At(x, y))
+		}
+	}
+	return img
+}
+
+func TestHintJoinsGroups(t *testing.T) {
+	g := largeGame(t, 2, 2)
+	followHint(t, g, jigsaw.Hint{Piece: 2, NextTo: 1, Position: image.Pt(10010, 0)})
+	followHint(t, g, jigsaw.Hint{Piece: 4, NextTo: 3, Position: image.Pt(10010, 50)})
+	//both halves are solved so there is no piece on its own to hint at
+	h, err := g.Hint(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, h.Piece)
+	assert.Equal(t, 3, h.NextTo)
+	followHint(t, g, h)
+	assert.True(t, g.Complete(), "expected following the hint to join the halves")
+	_, err = g.Hint(nil)
+	assert.Equal(t, jigsaw.ErrNoHint, err)
+}
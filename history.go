@@ -0,0 +1,127 @@
+package jigsaw
+
+import "errors"
+
+// DEFAULT_HISTORY_LIMIT is how many moves a new game can undo. A piece dragged about counts as one move
+// until it is dropped
+const DEFAULT_HISTORY_LIMIT = 100
+
+var (
+	ErrNothingToUndo = errors.New("there is nothing to undo")
+	ErrNothingToRedo = errors.New("there is nothing to redo")
+)
+
+// command is a single move, rotation or drop. It holds the state of every piece it changed from before and
+// after, and for a drop every join it made, so it can be undone and redone. The player who made it keeps
+// credit for its joins only while it is not undone. A move is dragging until its pieces are dropped, more
+// moves of the same pieces by the same player until then are part of it
+type command struct {
+	player   string
+	before   []PieceState
+	after    []PieceState
+	merges   []merge
+	dragging bool
+}
+
+// merge records a group of pieces being joined to the group of another piece
+type merge struct {
+	pieces []int
+	with   int
+}
+
+// Undo reverses the last move, rotation or drop, taking apart any groups a drop joined. There is one
+// history for the game, shared by all its players, so it is whatever anyone did last that is undone
+func (g *Game) Undo() error {
+	if len(g.undo) == 0 {
+		return ErrNothingToUndo
+	}
+	c := g.undo[len(g.undo)-1]
+	g.undo = g.undo[:len(g.undo)-1]
+	c.dragging = false
+	for i := len(c.merges) - 1; i >= 0; i-- {
+		g.groups.split(c.merges[i].pieces)
+	}
+	if len(c.merges) > 0 {
+		g.playerStats(c.player).Snaps -= len(c.merges)
+	}
+	g.setStates(c.before)
+	g.redo = append(g.redo, c)
+	return nil
+}
+
+// Redo puts back the last thing undone
+func (g *Game) Redo() error {
+	if len(g.redo) == 0 {
+		return ErrNothingToRedo
+	}
+	c := g.redo[len(g.redo)-1]
+	g.redo = g.redo[:len(g.redo)-1]
+	for _, m := range c.merges {
+		g.groups.union(m.with, m.pieces[0])
+	}
+	if len(c.merges) > 0 {
+		g.playerStats(c.player).Snaps += len(c.merges)
+	}
+	g.setStates(c.after)
+	g.undo = append(g.undo, c)
+	return nil
+}
+
+func (g *Game) CanUndo() bool {
+	return len(g.undo) > 0
+}
+
+func (g *Game) CanRedo() bool {
+	return len(g.redo) > 0
+}
+
+// record adds a new command to the history, forgetting the oldest once there are more than HistoryLimit.
+// Anything that had been undone can no longer be redone
+func (g *Game) record(c command) {
+	g.redo = nil
+	if g.HistoryLimit <= 0 {
+		return
+	}
+	g.undo = append(g.undo, c)
+	if over := len(g.undo) - g.HistoryLimit; over > 0 {
+		g.undo = append(g.undo[:0], g.undo[over:]...)
+	}
+}
+
+// drag records a move, as part of the last one when it is the same player still dragging the same pieces
+func (g *Game) drag(c command) {
+	if n := len(g.undo); n > 0 && len(g.redo) == 0 {
+		last := &g.undo[n-1]
+		if last.dragging && last.player == c.player && sameIndexes(last.after, c.after) {
+			last.after = c.after
+			return
+		}
+	}
+	c.dragging = true
+	g.record(c)
+}
+
+// endDrag stops the player's later moves being added to the last one
+func (g *Game) endDrag(player string) {
+	if n := len(g.undo); n > 0 && g.undo[n-1].player == player {
+		g.undo[n-1].dragging = false
+	}
+}
+
+func sameIndexes(a, b []PieceState) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].Index != b[i].Index {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *Game) setStates(states []PieceState) {
+	for _, s := range states {
+		*g.states[s.Index] = s
+	}
+}
@@ -5,20 +5,34 @@ import (
 	"fmt"
 	"image"
 	"math"
-
-	"image/color"
 )
 
 type Jigsaw struct {
 	Rows   int
+	Cols   int
+	Seed   int64
 	Pieces []*Piece
 	Path   string
 	Bounds image.Rectangle
+	Fit    *FitTransform
+	//Mode is MODE_EDGE or MODE_CENTER when only some of the pieces were kept
+	Mode string
 }
 
+// PieceJoint is the tab or blank on one side of a piece. Size is how big the tab is as a percentage of
+// the longer side of the piece and Offset how far it is moved along the side from the middle as a
+// fraction of the side, zero values use the cutter's tab size in the middle of the side. Wave makes the side
+// wavy, see waveAt, and is the same on both pieces that share the side. Neighbour is the Index of the
+// piece on the other side. Pieces that are not on a grid number their Side by the edges of their Outline
+// and give the two ends of the shared Edge on the board
 type PieceJoint struct {
-	External bool
-	Side     int
+	External  bool          `json:"external"`
+	Side      int           `json:"side"`
+	Neighbour int           `json:"neighbour,omitempty"`
+	Size      float64       `json:"size,omitempty"`
+	Offset    float64       `json:"offset,omitempty"`
+	Wave      []float64     `json:"wave,omitempty"`
+	Edge      []image.Point `json:"edge,omitempty"`
 }
 
 type Piece struct {
@@ -29,6 +43,7 @@ type Piece struct {
 	IsCorner         bool
 	IsEdge           bool
 	IsCenter         bool
+	IsWhimsy         bool
 	Name             string
 	Path             string
 	RightPieceIndex  int
@@ -37,9 +52,53 @@ type Piece struct {
 	TopPieceIndex    int
 	Index            int
 	Row              int
+	Col              int
+	Rotation         int
+	Outline          []image.Point
 	Board            image.Rectangle
 	Bounds           image.Rectangle
 	Image            image.Image
+	Back             image.Image
+	BackPath         string
+	Padding          Padding
+	//site is the point a piece from a Tiling is cut around
+	site vec
+	//cell is where a piece on a grid whose lines have been moved sits
+	cell image.Rectangle
+}
+
+// Neighbours returns the Index of each piece that shares a side with this one
+func (p *Piece) Neighbours() []int {
+	neighbours := make([]int, 0, 4)
+	for _, i := range []int{p.TopPieceIndex, p.RightPieceIndex, p.BottomPieceIndex, p.LeftPieceIndex} {
+		if i > 0 {
+			neighbours = append(neighbours, i)
+		}
+	}
+	//pieces that are not on a grid only know their neighbours from their joints
+	for _, j := range p.Joints {
+		if j.Neighbour > 0 && !containsIndex(neighbours, j.Neighbour) {
+			neighbours = append(neighbours, j.Neighbour)
+		}
+	}
+	return neighbours
+}
+
+// Sides is how many sides the piece has, its Outline's or four for a piece on a grid
+func (p *Piece) Sides() int {
+	if p.Outline != nil {
+		return len(p.Outline)
+	}
+	return 4
+}
+
+func containsIndex(indexes []int, index int) bool {
+	for _, i := range indexes {
+		if i == index {
+			return true
+		}
+	}
+	return false
 }
 
 func (p *Piece) TopRow() bool {
@@ -76,58 +135,43 @@ func (p *Piece) FarLeftVerticalRow() bool {
 	return false
 }
 
-type circle struct {
-	P           image.Point
-	R           int
-	image       image.Image
-	inverse     bool
-	InnerBounds image.Rectangle
-}
-
-func (c *circle) ColorModel() color.Model {
-	return color.AlphaModel
-}
-
-func (c *circle) Bounds() image.Rectangle {
-	return c.image.Bounds()
-}
-
-//TODO switch transparent for when the circle is external joint
-func (c *circle) At(x, y int) color.Color {
-	//c.p.X is the x point for the center of the circle
-	//c.p.Y is the y point for the center of the circle
-	xx, yy, rr := float64(x-c.P.X)+0.5, float64(y-c.P.Y)+0.5, float64(c.R)
-	if xx*xx+yy*yy < rr*rr { //the point of ref by x,y is within the diameter of the circle
-		if c.inverse {
-			return c.image.At(x, y)
-		} else {
-			return color.Transparent
-		}
-	}
-	if c.inverse {
-		if y < c.InnerBounds.Min.Y || x < c.InnerBounds.Min.X || y > c.InnerBounds.Max.Y || x > c.InnerBounds.Max.X {
-
-			return c.image.At(x, y)
-		} else {
-			return color.Transparent
-		}
-
-	} else {
-		return c.image.At(x, y)
-	}
-
-}
-
+// JigsawBuilder cuts an image into a jigsaw. Each edge's tab gets a size between MinTabSize and MaxTabSize
+// and is moved along the edge by up to TabJitter, both picked from the Seed. When they are zero every tab
+// is the cutter's size in the middle of its edge, and a MinTabSize of zero on its own makes every tab
+// MaxTabSize. EdgeStyle EDGE_WAVY makes the edges between pieces wavy by up to WaveAmplitude percent of
+// their length, 0 uses DEFAULT_WAVE_AMPLITUDE. A Tiling lays the pieces out in some other way than a grid
+// of NumRows, which is then not used. Whimsies are cut out of the finished pieces and added after them.
+// Saliency moves the lines and tabs of a grid away from the parts of the image that should not be cut
+// through. Mode MODE_EDGE keeps only the pieces around the edge and MODE_CENTER only the rest, numbered as
+// they are in the whole jigsaw. Back is an image the same size as the one being cut that is cut into the
+// other side of every piece, see backPiece. Once the pieces have been rotated Bevel shades their edges,
+// front and back, then Stroke outlines them and Shadow drops a shadow behind them
 type JigsawBuilder struct {
 	PieceCutter     PieceCutter
 	PieceMarker     PieceMarker
 	NumPieces       int
 	NumRows         int
 	NumPiecesPerRow int
+	Rotate          bool
+	Seed            int64
+	Fit             *Fit
+	MinTabSize      float64
+	MaxTabSize      float64
+	TabJitter       float64
+	EdgeStyle       string
+	WaveAmplitude   float64
+	Tiling          Tiling
+	Whimsies        []Whimsy
+	Saliency        *Saliency
+	Mode            string
+	Back            image.Image
+	Bevel           *Bevel
+	Stroke          *Stroke
+	Shadow          *Shadow
 	baseImage       image.Image
 }
 
-//todo break up
+// todo break up
 func (jb *JigsawBuilder) BuildPieces() ([]*Piece, error) {
 
 	piecesPerLine := jb.NumPieces / jb.NumRows
@@ -135,6 +179,9 @@ func (jb *JigsawBuilder) BuildPieces() ([]*Piece, error) {
 	rect := jb.baseImage.Bounds()
 	pieceHeight := rect.Max.Y / jb.NumRows
 	pieceWidth := rect.Max.X / piecesPerLine
+	if pieceHeight == 0 || pieceWidth == 0 {
+		return nil, fmt.Errorf("image is too small to be cut into %d pieces", jb.NumPieces)
+	}
 	pieces := make([]*Piece, 0)
 
 	isCorner := func(points []image.Point) bool {
@@ -187,8 +234,20 @@ func (jb *JigsawBuilder) BuildPieces() ([]*Piece, error) {
 			leftX, rightX := X(currentPosX, pieceWidth)
 			points := []image.Point{image.Pt(leftX, bottomY), image.Pt(rightX, bottomY), image.Pt(leftX, topY), image.Pt(rightX, topY)}
 			corner := isCorner(points)
-			p := &Piece{Height: pieceHeight, Width: pieceWidth, Points: points, IsCorner: corner, IsEdge: isEdge(points), Name: fmt.Sprintf("piece%d", pieceNum), Index: pieceNum, Row: i, Board: jb.baseImage.Bounds(), IsCenter: isCenter(points), Joints: nil}
+			p := &Piece{Height: pieceHeight, Width: pieceWidth, Points: points, IsCorner: corner, IsEdge: isEdge(points), Name: fmt.Sprintf("piece%d", pieceNum), Index: pieceNum, Row: i, Col: j, Board: jb.baseImage.Bounds(), IsCenter: isCenter(points), Joints: nil}
 			p.Bounds = image.Rect(points[0].X, points[0].Y, points[3].X, points[3].Y)
+			if j > 0 {
+				p.LeftPieceIndex = pieceNum - 1
+			}
+			if j < piecesPerLine-1 {
+				p.RightPieceIndex = pieceNum + 1
+			}
+			if i > 0 {
+				p.TopPieceIndex = pieceNum - piecesPerLine
+			}
+			if i < jb.NumRows-1 {
+				p.BottomPieceIndex = pieceNum + piecesPerLine
+			}
 			pieces = append(pieces, p)
 			pieceNum++
 		}
@@ -198,6 +257,12 @@ func (jb *JigsawBuilder) BuildPieces() ([]*Piece, error) {
 }
 
 func (jb *JigsawBuilder) buildRows() error {
+	if jb.NumRows > 0 {
+		if jb.NumPieces%jb.NumRows != 0 {
+			return errors.New("num pieces should be a whole number of rows")
+		}
+		return nil
+	}
 	//we are only interested in whole numbers
 	sqr := int(math.Sqrt(float64(jb.NumPieces)))
 	if jb.NumPieces%sqr != 0 {
@@ -210,25 +275,158 @@ func (jb *JigsawBuilder) buildRows() error {
 func (jb *JigsawBuilder) Build() (Jigsaw, error) {
 	jig := Jigsaw{}
 	jig.Bounds = jb.baseImage.Bounds()
-	err := jb.buildRows()
-	if err != nil {
+	if jb.Tiling == nil {
+		if err := jb.buildRows(); err != nil {
+			return jig, err
+		}
+		jig.Rows = jb.NumRows
+		jig.Cols = jb.NumPieces / jb.NumRows
+	} else if jb.EdgeStyle == EDGE_WAVY {
+		return jig, ErrWavyTiling
+	}
+	if err := jb.validateEdges(); err != nil {
+		return jig, err
+	}
+	if err := jb.validateTabs(); err != nil {
+		return jig, err
+	}
+	if err := jb.Saliency.validate(jb); err != nil {
 		return jig, err
 	}
-	pieces, err := jb.BuildPieces()
+	if err := jb.validateMode(); err != nil {
+		return jig, err
+	}
+	if err := jb.validateBack(); err != nil {
+		return jig, err
+	}
+	if err := jb.Bevel.validate(); err != nil {
+		return jig, err
+	}
+	if err := jb.Stroke.validate(); err != nil {
+		return jig, err
+	}
+	if err := jb.Shadow.validate(); err != nil {
+		return jig, err
+	}
+	if jb.Fit != nil {
+		//a tiling does not need the image to divide into whole pieces
+		img, transform, err := jb.Fit.Apply(jb.baseImage, maxInt(jig.Rows, 1), maxInt(jig.Cols, 1))
+		if err != nil {
+			return jig, err
+		}
+		jb.baseImage = img
+		jig.Bounds = img.Bounds()
+		jig.Fit = &transform
+		if jb.Back != nil {
+			//the back is the same size so it is fitted the same way
+			if jb.Back, _, err = jb.Fit.Apply(jb.Back, maxInt(jig.Rows, 1), maxInt(jig.Cols, 1)); err != nil {
+				return jig, err
+			}
+		}
+	}
+	if err := validateWhimsies(jb.baseImage.Bounds(), jb.Whimsies); err != nil {
+		return jig, err
+	}
+	var pieces []*Piece
+	var err error
+	if jb.Tiling == nil {
+		pieces, err = jb.BuildPieces()
+	} else {
+		pieces, err = jb.Tiling.Tile(jb.baseImage.Bounds(), jb.NumPieces, jb.Seed)
+	}
 	if err != nil {
 		return jig, err
 	}
+	var saliency *energy
+	if jb.Saliency != nil {
+		saliency = jb.Saliency.newEnergy(jb.baseImage)
+		jb.Saliency.moveGrid(pieces, jig.Rows, jig.Cols, saliency)
+	}
 	pieces = jb.PieceMarker.MarkPieces(pieces, jb.NumPiecesPerRow, jb.NumRows)
-	pieces, err = jb.PieceCutter.CutPieces(jb.baseImage, pieces)
+	jb.varyTabs(pieces)
+	jb.waveEdges(pieces)
+	if jb.Saliency != nil {
+		tabSize := PERCENTAGE
+		if cutter, ok := jb.PieceCutter.(JigsawPieceCutter); ok {
+			tabSize = cutter.tabSize()
+		}
+		jb.Saliency.placeTabs(pieces, tabSize, saliency)
+	}
+	from := jb.baseImage
+	shaped := len(jb.Whimsies) > 0 || jb.Back != nil
+	if shaped {
+		//the whimsies are cut out of the shapes of the pieces, which are then filled in from the front and back
+		from = solid{from.Bounds()}
+	}
+	pieces, err = jb.PieceCutter.CutPieces(from, pieces)
 	if err != nil {
 		return jig, err
 	}
+	if len(jb.Whimsies) > 0 {
+		if pieces, err = addWhimsies(from.Bounds(), pieces, jb.Whimsies); err != nil {
+			return jig, err
+		}
+	}
+	if shaped {
+		for _, p := range pieces {
+			if jb.Back != nil && p.Image != nil {
+				p.Back = backPiece(jb.Back, from.Bounds(), p)
+			}
+			fillPiece(jb.baseImage, p)
+		}
+	}
+	if jb.Rotate {
+		if err := rotatePieces(pieces, jb.Seed); err != nil {
+			return jig, err
+		}
+	}
+	//lit and shadowed once turned so every piece looks the same whichever way up it goes
+	if jb.Bevel != nil {
+		for _, p := range pieces {
+			if p.Image != nil {
+				p.Image = jb.Bevel.shade(p.Image)
+			}
+			if p.Back != nil {
+				p.Back = jb.Bevel.shade(p.Back)
+			}
+		}
+	}
+	if jb.Stroke != nil {
+		for _, p := range pieces {
+			if p.Image != nil {
+				p.Image = jb.Stroke.draw(p.Image)
+			}
+			if p.Back != nil {
+				p.Back = jb.Stroke.draw(p.Back)
+			}
+		}
+	}
+	if jb.Shadow != nil {
+		for _, p := range pieces {
+			if p.Image != nil {
+				p.Image = jb.Shadow.drop(p.Image)
+				p.Padding = jb.Shadow.pad()
+			}
+		}
+	}
+	if shaped || jb.Rotate || jb.Bevel != nil || jb.Stroke != nil || jb.Shadow != nil {
+		if err := savePieces(pieces); err != nil {
+			return jig, err
+		}
+	}
+	if pieces, err = keepPieces(pieces, jb.Mode); err != nil {
+		return jig, err
+	}
+	if jb.Mode == MODE_EDGE || jb.Mode == MODE_CENTER {
+		jig.Mode = jb.Mode
+	}
+	jig.Seed = jb.Seed
 	jig.Pieces = pieces
 	return jig, nil
 }
 
 func NewJigsawBuilder(img image.Image, numPieces int) *JigsawBuilder {
-	return &JigsawBuilder{NumPieces: numPieces, baseImage: img, PieceCutter: JigsawPieceCutter{}, PieceMarker: JigsawPieceMarker{}}
+	return &JigsawBuilder{NumPieces: numPieces, baseImage: img, PieceCutter: JigsawPieceCutter{OutputDir: DEFAULT_OUTPUT_DIR}, PieceMarker: JigsawPieceMarker{}}
 }
 func NewJigsawBuilderWithPieceCutter(img image.Image, numPieces int, cutter JigsawPieceCutter) *JigsawBuilder {
 	return &JigsawBuilder{NumPieces: numPieces, baseImage: img, PieceCutter: cutter, PieceMarker: JigsawPieceMarker{}}
@@ -1,12 +1,16 @@
 package jigsaw_test
 
 import (
-	"github.com/maleck13/jigsaw"
-	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
 	"image"
+	"image/color"
+	"image/draw"
 	"image/jpeg"
 	"os"
 	"testing"
+	"testing/quick"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
 )
 
 const JPG_SAMPLE string = "./samples/firetruck.jpg"
@@ -19,251 +23,173 @@ func openImage(t *testing.T, path string) image.Image {
 	return img
 }
 
-//func TestCreateJigsaw(t *testing.T) {
-//	img := openImage(t, JPG_SAMPLE)
-//	builder := jigsaw.NewJigsawBuilder(img, 24)
-//	jig, err := builder.Build()
-//	assert.NoError(t, err, "did not expect an error")
-//	assert.NotNil(t, jig, "expected jigsaw")
-//}
-//
-//func TestJigsawShouldHave4Corners(t *testing.T) {
-//	img := openImage(t, JPG_SAMPLE)
-//	builder := jigsaw.NewJigsawBuilder(img, 24)
-//	jig, err := builder.Build()
-//	assert.NoError(t, err, "did not expect an error")
-//	assert.NotNil(t, jig, "expected jigsaw")
-//	corners := 0
-//	for _, p := range jig.Pieces {
-//		if p.IsCorner {
-//			corners++
-//		}
-//	}
-//	assert.True(t, corners == 4, "expected four corners")
-//}
-//
-//func Test8PieceJigsaw(t *testing.T) {
-//	img := openImage(t, JPG_SAMPLE)
-//	builder := jigsaw.NewJigsawBuilder(img, 12)
-//	jig, err := builder.Build()
-//	assert.NoError(t, err, "did not expect an error")
-//	assert.NotNil(t, jig, "expected jigsaw")
-//	edgeCount := 0
-//	for _, p := range jig.Pieces {
-//		if p.IsEdge && !p.IsCorner {
-//			edgeCount++
-//		}
-//	}
-//	assert.Equal(t, 6, edgeCount, "expected 6 non corner edge pieces")
-//}
-
-//func Test48PieceJigsaw(t *testing.T) {
-//	img := openImage(t, JPG_SAMPLE)
-//	builder := jigsaw.NewJigsawBuilder(img, 48)
-//	jig, err := builder.Build()
-//	assert.NoError(t, err, "did not expect an error")
-//	assert.NotNil(t, jig, "expected jigsaw")
-//	edgeCount := 0
-//	cornerCount := 0
-//	for _, p := range jig.Pieces {
-//		if p.IsCorner {
-//			cornerCount++
-//		}
-//		if p.IsEdge{
-//			edgeCount++
-//		}
-//	}
-//	assert.Equal(t, 4, cornerCount, "expected 4 corner pieces")
-//	assert.Equal(t, 24, edgeCount, "expected 8 edge pieces")
-//
-//}
-
 func Test4pieceJigsaw(t *testing.T) {
 	img := openImage(t, JPG_SAMPLE)
 	builder := jigsaw.NewJigsawBuilder(img, 24)
 	jig, err := builder.Build()
 	assert.NoError(t, err, "did not expect an error")
 	assert.NotNil(t, jig, "expected jigsaw")
-	//	for _, p := range jig.Pieces {
-	//		assert.True(t, p.IsCorner, "should be a corner")
-	//	}
 }
 
-//func TestShapePiece0and1(t *testing.T) {
-//	img := openImage(t, JPG_SAMPLE)
-//	builder := jigsaw.NewJigsawBuilder(img, 12)
-//	builder.NumRows = 3
-//	pieces, err := builder.BuildPieces()
-//	assert.NoError(t, err, "not expected error")
-//	piece := pieces[0]
-//	piece.Joints = []jigsaw.PieceJoint{
-//		jigsaw.PieceJoint{
-//			Side:     0,
-//			External: false,
-//		},
-//		jigsaw.PieceJoint{
-//			Side:     1,
-//			External: false,
-//		},
-//		jigsaw.PieceJoint{
-//			Side:     2,
-//			External: false,
-//		},
-//		jigsaw.PieceJoint{
-//			Side:     3,
-//			External: true,
-//		},
-//	}
-//	pieces, err = builder.PieceCutter.CutPieces(img, pieces)
-//	assert.NotNil(t, pieces, "pieces expected")
-//
-//	piece.Name = "testpiece0"
-//
-//	pieceCutter := jigsaw.JigsawPieceCutter{}
-//	pieceCutter.ShapePiece(piece)
-//
-//}
-//
-//func TestShapePieceSide1(t *testing.T) {
-//	img := openImage(t, JPG_SAMPLE)
-//	builder := jigsaw.NewJigsawBuilder(img, 12)
-//	builder.NumRows = 3
-//	pieces, err := builder.BuildPieces()
-//	assert.NoError(t, err, "not expected error")
-//	pieces, err = builder.PieceCutter.CutPieces(img, pieces)
-//	assert.NotNil(t, pieces, "pieces expected")
-//	piece := pieces[0]
-//	piece.Name = "testpiece1"
-//	piece.Joints = []jigsaw.PieceJoint{
-//		jigsaw.PieceJoint{
-//			Side:     1,
-//			External: true,
-//		},
-//	}
-//	pieceCutter := jigsaw.JigsawPieceCutter{}
-//	pieceCutter.ShapePiece(piece)
-//
-//}
-//
-//func TestShapePieceSide2(t *testing.T) {
-//	img := openImage(t, JPG_SAMPLE)
-//	builder := jigsaw.NewJigsawBuilder(img, 12)
-//	builder.NumRows = 3
-//	pieces, err := builder.BuildPieces()
-//	assert.NoError(t, err, "not expected error")
-//	piece := pieces[0]
-//	piece.Name = "testpiece2"
-//	piece.Joints = []jigsaw.PieceJoint{
-//		jigsaw.PieceJoint{
-//			Side:     2,
-//			External: true,
-//		},
-//	}
-//	pieces, err = builder.PieceCutter.CutPieces(img, pieces)
-//	assert.NotNil(t, pieces, "pieces expected")
-//
-//	pieceCutter := jigsaw.JigsawPieceCutter{}
-//	pieceCutter.ShapePiece(piece)
-//
-//}
-//
-//func TestShapePieceSide3(t *testing.T) {
-//	img := openImage(t, JPG_SAMPLE)
-//	builder := jigsaw.NewJigsawBuilder(img, 12)
-//	builder.NumRows = 3
-//	pieces, err := builder.BuildPieces()
-//	assert.NoError(t, err, "not expected error")
-//	pieces, err = builder.PieceCutter.CutPieces(img, pieces)
-//	assert.NotNil(t, pieces, "pieces expected")
-//	piece := pieces[0]
-//	piece.Name = "testpiece3"
-//	piece.Joints = []jigsaw.PieceJoint{
-//		jigsaw.PieceJoint{
-//			Side:     3,
-//			External: true,
-//		},
-//	}
-//	pieceCutter := jigsaw.JigsawPieceCutter{}
-//	pieceCutter.ShapePiece(piece)
-//
-//}
+// grid is a random puzzle layout used by the property tests. Rows and columns are kept at 2 or more
+// as a single row or column has no corners to speak of
+type grid struct {
+	rows, cols    int
+	width, height int
+}
+
+func newGrid(rows, cols, width, height uint16) grid {
+	g := grid{rows: 2 + int(rows%15), cols: 2 + int(cols%15)}
+	g.width = g.cols + int(width%2000)
+	g.height = g.rows + int(height%2000)
+	return g
+}
+
+func buildMarkedPieces(t *testing.T, g grid) []*jigsaw.Piece {
+	builder := jigsaw.NewJigsawBuilder(image.NewRGBA(image.Rect(0, 0, g.width, g.height)), g.rows*g.cols)
+	builder.NumRows = g.rows
+	pieces, err := builder.BuildPieces()
+	assert.NoError(t, err, "did not expect an error building pieces")
+	return builder.PieceMarker.MarkPieces(pieces, builder.NumPiecesPerRow, builder.NumRows)
+}
+
+// buildJigsaw cuts img into pieces with the cutter, after configure has set up the rest of the builder
+func buildJigsaw(t *testing.T, img image.Image, pieces int, cutter jigsaw.JigsawPieceCutter, configure func(b *jigsaw.JigsawBuilder)) jigsaw.Jigsaw {
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(img, pieces, cutter)
+	configure(builder)
+	jig, err := builder.Build()
+	assert.NoError(t, err, "did not expect an error building")
+	return jig
+}
+
+func joint(p *jigsaw.Piece, side int) (jigsaw.PieceJoint, bool) {
+	for _, j := range p.Joints {
+		if j.Side == side {
+			return j, true
+		}
+	}
+	return jigsaw.PieceJoint{}, false
+}
+
+func TestPropertyGridClassification(t *testing.T) {
+	property := func(rows, cols, width, height uint16) bool {
+		g := newGrid(rows, cols, width, height)
+		pieces := buildMarkedPieces(t, g)
+		corners, edges := 0, 0
+		for _, p := range pieces {
+			onBorder := p.Row == 0 || p.Col == 0 || p.Row == g.rows-1 || p.Col == g.cols-1
+			if p.IsCorner {
+				corners++
+			}
+			if p.IsEdge {
+				edges++
+			}
+			if p.IsEdge != onBorder || p.IsCenter == onBorder {
+				t.Logf("%+v: piece %s at row %d col %d misclassified", g, p.Name, p.Row, p.Col)
+				return false
+			}
+		}
+		if corners != 4 || edges != 2*(g.rows+g.cols)-4 {
+			t.Logf("%+v: got %d corners and %d edges", g, corners, edges)
+			return false
+		}
+		return len(pieces) == g.rows*g.cols
+	}
+	assert.NoError(t, quick.Check(property, nil))
+}
+
+func TestPropertyJointMarking(t *testing.T) {
+	property := func(rows, cols, width, height uint16) bool {
+		g := newGrid(rows, cols, width, height)
+		pieces := buildMarkedPieces(t, g)
+		at := func(row, col int) *jigsaw.Piece {
+			return pieces[row*g.cols+col]
+		}
+		for _, p := range pieces {
+			border := map[int]bool{
+				jigsaw.TOP_SIDE:    p.Row == 0,
+				jigsaw.RIGHT_SIDE:  p.Col == g.cols-1,
+				jigsaw.BOTTOM_SIDE: p.Row == g.rows-1,
+				jigsaw.LEFT_SIDE:   p.Col == 0,
+			}
+			for side, onBorder := range border {
+				if _, ok := joint(p, side); ok == onBorder {
+					t.Logf("%+v: piece %s side %d has joint %v on border %v", g, p.Name, side, ok, onBorder)
+					return false
+				}
+			}
+			if p.Col < g.cols-1 {
+				right, _ := joint(p, jigsaw.RIGHT_SIDE)
+				left, _ := joint(at(p.Row, p.Col+1), jigsaw.LEFT_SIDE)
+				if right.External == left.External {
+					t.Logf("%+v: piece %s right edge does not have one tab and one blank", g, p.Name)
+					return false
+				}
+			}
+			if p.Row < g.rows-1 {
+				bottom, _ := joint(p, jigsaw.BOTTOM_SIDE)
+				top, _ := joint(at(p.Row+1, p.Col), jigsaw.TOP_SIDE)
+				if bottom.External == top.External {
+					t.Logf("%+v: piece %s bottom edge does not have one tab and one blank", g, p.Name)
+					return false
+				}
+			}
+		}
+		return true
+	}
+	assert.NoError(t, quick.Check(property, nil))
+}
+
+func TestBuildPiecesImageTooSmall(t *testing.T) {
+	builder := jigsaw.NewJigsawBuilder(image.NewRGBA(image.Rect(0, 0, 3, 3)), 16)
+	builder.NumRows = 4
+	_, err := builder.BuildPieces()
+	assert.Error(t, err, "expected an error when pieces would be empty")
+}
+
+func TestJointStyles(t *testing.T) {
+	white := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	draw.Draw(white, white.Bounds(), image.NewUniform(color.White), image.ZP, draw.Src)
+	cut := func(cutter jigsaw.JigsawPieceCutter) *jigsaw.Piece {
+		builder := jigsaw.NewJigsawBuilderWithPieceCutter(white, 4, cutter)
+		jig, err := builder.Build()
+		assert.NoError(t, err, "did not expect an error building jigsaw")
+		return jig.Pieces[0]
+	}
+	//the top left piece has tabs on its right and bottom
+	assert.Equal(t, image.Rect(0, 0, 110, 110), cut(jigsaw.JigsawPieceCutter{}).Bounds)
+	assert.Equal(t, image.Rect(0, 0, 120, 120), cut(jigsaw.JigsawPieceCutter{TabSize: 20, JointStyle: jigsaw.JOINT_SQUARE}).Bounds)
+	assert.Equal(t, image.Rect(0, 0, 100, 100), cut(jigsaw.JigsawPieceCutter{JointStyle: jigsaw.JOINT_FLAT}).Bounds)
+
+	//a square tab fills the corners a round one leaves empty
+	round, square := cut(jigsaw.JigsawPieceCutter{}), cut(jigsaw.JigsawPieceCutter{JointStyle: jigsaw.JOINT_SQUARE})
+	_, _, _, a := round.Image.At(108, 42).RGBA()
+	assert.Equal(t, uint32(0), a, "expected the corner of a round tab to be cut away")
+	_, _, _, a = square.Image.At(108, 42).RGBA()
+	assert.NotEqual(t, uint32(0), a, "expected the corner of a square tab to be kept")
 
-//func TestJigsawShouldHave16EdgePieces(t *testing.T) {
-//	img := openImage(t, JPG_SAMPLE)
-//	builder := jigsaw.NewJigsawBuilder(img, 24)
-//	jig, err := builder.Build()
-//	assert.NoError(t, err, "did not expect an error")
-//	assert.NotNil(t, jig, "expected jigsaw")
-//	edge := 0
-//	for _, p := range jig.Pieces {
-//		if p.IsEdge {
-//			edge++
-//		}
-//	}
-//	assert.True(t, edge == 16, "expected 16 edge pieces")
-//}
-//
-//func TestInvalidNumberOfPieces(t *testing.T) {
-//	img := openImage(t, JPG_SAMPLE)
-//	builder := jigsaw.NewJigsawBuilder(img, 10)
-//	jig, err := builder.Build()
-//	assert.Error(t, err, "expected an error")
-//	assert.NotNil(t, jig, "expected jigsaw")
-//
-//}
-//
-//func TestShapePiece(t *testing.T) {
-//	img := openImage(t, "./piece35.jpg")
-//	cuts := []jigsaw.CutType{jigsaw.CutType{
-//		Edge:1,
-//		Internal:true,
-//	},
-//	jigsaw.CutType{
-//		Edge:2,
-//		Internal:false,
-//	}}
-//	jigsaw.ShapePieces(img,cuts)
-//}
-//
-//func TestCornersHave2Neighbours(t *testing.T)  {
-//	img := openImage(t, JPG_SAMPLE)
-//	builder := jigsaw.NewJigsawBuilder(img, 24)
-//	jig, err := builder.Build()
-//	assert.NoError(t, err, "did not expect an error")
-//	assert.NotNil(t, jig, "expected jigsaw")
-//	for _,p:= range jig.Pieces{
-//		if p.IsCorner && p.TopRow(){
-//			fmt.Print(p.RightPieceIndex, p.LeftPieceIndex,p.BottomPieceIndex)
-//		}
-//	}
-//}
-//
-//func TestCenterPieces12(t *testing.T)  {
-//	img := openImage(t, JPG_SAMPLE)
-//	builder := jigsaw.NewJigsawBuilder(img, 12)
-//	jig, err := builder.Build()
-//	assert.NoError(t, err, "did not expect an error")
-//	assert.NotNil(t, jig, "expected jigsaw")
-//	center :=0
-//	for _,p:= range jig.Pieces{
-//		if p.IsCenter{
-//			center++
-//		}
-//	}
-//	assert.Equal(t,2,center,"expected 2 center pieces")
-//}
-//
-//func TestCenterPieces24(t *testing.T)  {
-//	img := openImage(t, JPG_SAMPLE)
-//	builder := jigsaw.NewJigsawBuilder(img, 24)
-//	jig, err := builder.Build()
-//	assert.NoError(t, err, "did not expect an error")
-//	assert.NotNil(t, jig, "expected jigsaw")
-//	center :=0
-//	for _,p:= range jig.Pieces{
-//		if p.IsCenter{
-//			center++
-//		}
-//	}
-//	assert.Equal(t,8,center,"expected 2 center pieces")
-//}
+	_, err := jigsaw.NewJigsawBuilderWithPieceCutter(image.NewRGBA(image.Rect(0, 0, 200, 200)), 4, jigsaw.JigsawPieceCutter{JointStyle: "zigzag"}).Build()
+	assert.Error(t, err, "expected an unknown joint style to be rejected")
+}
+
+// the marker used to pick joints by which corner or edge a piece was on and gave the bottom row a tab on
+// its top side, the same as the bottom of the row above, so the pieces could not fit. Joints are now
+// picked from the Row and Col of the piece alone
+func TestMarkPiecesByRowAndCol(t *testing.T) {
+	pieces := buildMarkedPieces(t, grid{rows: 3, cols: 3, width: 300, height: 300})
+	assert.Equal(t, []jigsaw.PieceJoint{
+		{Side: jigsaw.BOTTOM_SIDE, External: true, Neighbour: 6},
+		{Side: jigsaw.LEFT_SIDE, External: false, Neighbour: 2},
+	}, pieces[2].Joints, "expected the top right corner to join the pieces beside and below it")
+	assert.Equal(t, []jigsaw.PieceJoint{
+		{Side: jigsaw.RIGHT_SIDE, External: true, Neighbour: 6},
+		{Side: jigsaw.BOTTOM_SIDE, External: true, Neighbour: 8},
+		{Side: jigsaw.LEFT_SIDE, External: false, Neighbour: 4},
+		{Side: jigsaw.TOP_SIDE, External: false, Neighbour: 2},
+	}, pieces[4].Joints)
+	assert.Equal(t, []jigsaw.PieceJoint{
+		{Side: jigsaw.RIGHT_SIDE, External: true, Neighbour: 9},
+		{Side: jigsaw.LEFT_SIDE, External: false, Neighbour: 7},
+		{Side: jigsaw.TOP_SIDE, External: false, Neighbour: 5},
+	}, pieces[7].Joints, "expected the bottom row to have a blank on top")
+}
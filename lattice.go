@@ -0,0 +1,78 @@
+package jigsaw
+
+import (
+	"fmt"
+	"image"
+	"math"
+)
+
+// HexTiling cuts the board into regular hexagons, pointy topped, in rows offset by half a hexagon.
+// They are sized so there are about as many as the pieces asked for and the pattern is centred on the
+// board, the pieces around the border being the parts of hexagons that are on it
+type HexTiling struct{}
+
+func (HexTiling) Tile(board image.Rectangle, numPieces int, seed int64) ([]*Piece, error) {
+	if err := checkLattice(board, numPieces); err != nil {
+		return nil, err
+	}
+	//a hexagon is spacing wide and its rows are rise apart
+	area := float64(board.Dx() * board.Dy())
+	spacing := math.Sqrt(2 * area / (math.Sqrt(3) * float64(numPieces)))
+	rise := spacing * math.Sqrt(3) / 2
+	rows, cols := latticeCount(board.Dy(), rise), latticeCount(board.Dx(), spacing)
+	x0 := float64(board.Min.X) + (float64(board.Dx())-float64(cols-1)*spacing-spacing/2)/2
+	y0 := float64(board.Min.Y) + (float64(board.Dy())-float64(rows-1)*rise)/2
+	sites := make([]vec, 0, rows*cols)
+	for j := 0; j < rows; j++ {
+		for k := 0; k < cols; k++ {
+			x := x0 + float64(k)*spacing + float64(j%2)*spacing/2
+			sites = append(sites, vec{x, y0 + float64(j)*rise})
+		}
+	}
+	return sitePieces(board, sites)
+}
+
+// TriangleTiling cuts the board into rows of equilateral triangles, pointing up and down in turn. Like
+// HexTiling there are about as many as the pieces asked for, centred on the board
+type TriangleTiling struct{}
+
+func (TriangleTiling) Tile(board image.Rectangle, numPieces int, seed int64) ([]*Piece, error) {
+	if err := checkLattice(board, numPieces); err != nil {
+		return nil, err
+	}
+	//the sides of a triangle are side long, each takes up half of that along its row and rows are rise apart.
+	//The cells around the centres of the triangles are the triangles themselves
+	area := float64(board.Dx() * board.Dy())
+	side := math.Sqrt(4 * area / (math.Sqrt(3) * float64(numPieces)))
+	rise := side * math.Sqrt(3) / 2
+	rows, cols := latticeCount(board.Dy(), rise), latticeCount(board.Dx(), side/2)
+	x0 := float64(board.Min.X) + (float64(board.Dx())-float64(cols-1)*side/2)/2
+	y0 := float64(board.Min.Y) + (float64(board.Dy())-float64(rows)*rise)/2
+	sites := make([]vec, 0, rows*cols)
+	for j := 0; j < rows; j++ {
+		for k := 0; k < cols; k++ {
+			//an upward triangle's centre is a third of the way up from its base
+			y := y0 + float64(j)*rise + rise/3
+			if (j+k)%2 == 0 {
+				y += rise / 3
+			}
+			sites = append(sites, vec{x0 + float64(k)*side/2, y})
+		}
+	}
+	return sitePieces(board, sites)
+}
+
+func checkLattice(board image.Rectangle, numPieces int) error {
+	if numPieces < 1 {
+		return fmt.Errorf("can not cut %d pieces", numPieces)
+	}
+	if board.Dx()*board.Dy() < numPieces {
+		return fmt.Errorf("image is too small to be cut into %d pieces", numPieces)
+	}
+	return nil
+}
+
+// latticeCount is how many rows or columns spacing apart best fill length, at least one
+func latticeCount(length int, spacing float64) int {
+	return maxInt(1, int(math.Floor(float64(length)/spacing+0.5)))
+}
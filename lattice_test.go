@@ -0,0 +1,74 @@
+package jigsaw_test
+
+import (
+	"image"
+	"image/color"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func latticeJigsaw(t *testing.T, tiling jigsaw.Tiling, cutter jigsaw.JigsawPieceCutter) jigsaw.Jigsaw {
+	return buildJigsaw(t, filled(300, 200, color.White), 40, cutter, func(b *jigsaw.JigsawBuilder) {
+		b.Tiling = tiling
+		b.MaxTabSize, b.TabJitter = 12, 0.1
+	})
+}
+
+func TestLatticeShapes(t *testing.T) {
+	for _, c := range []struct {
+		tiling jigsaw.Tiling
+		sides  int
+	}{
+		{jigsaw.HexTiling{}, 6},
+		{jigsaw.TriangleTiling{}, 3},
+	} {
+		jig := latticeJigsaw(t, c.tiling, jigsaw.JigsawPieceCutter{JointStyle: jigsaw.JOINT_FLAT})
+		assert.InDelta(t, 40, len(jig.Pieces), 8, "expected about the pieces asked for")
+		inside := 0
+		for _, p := range jig.Pieces {
+			if p.IsEdge {
+				continue
+			}
+			inside++
+			assert.Equal(t, c.sides, p.Sides(), "expected piece %d to have %d sides", p.Index, c.sides)
+			assert.Len(t, p.Joints, c.sides, "expected a joint on every side of piece %d", p.Index)
+			sides := map[int]bool{}
+			for _, j := range p.Joints {
+				sides[j.Side] = true
+			}
+			assert.Len(t, sides, c.sides, "expected the joints to be on different sides")
+		}
+		assert.True(t, inside > 0, "expected some pieces away from the border")
+	}
+	assert.Equal(t, 4, (&jigsaw.Piece{}).Sides())
+}
+
+func TestLatticePiecesFitTogether(t *testing.T) {
+	for _, tiling := range []jigsaw.Tiling{jigsaw.HexTiling{}, jigsaw.TriangleTiling{}} {
+		for _, cutter := range []jigsaw.JigsawPieceCutter{{}, {JointStyle: jigsaw.JOINT_SQUARE}} {
+			jig := latticeJigsaw(t, tiling, cutter)
+			covered := coverage(jig)
+			bad := 0
+			for y := 0; y < 200; y++ {
+				for x := 0; x < 300; x++ {
+					if covered[image.Pt(x, y)] != 1 {
+						bad++
+					}
+				}
+			}
+			assert.Equal(t, 0, bad, "expected every pixel to be in exactly one piece of %T", tiling)
+			assert.Len(t, covered, 300*200, "expected nothing outside the board")
+
+			layout := &jigsaw.Layout{}
+			for i, p := range jig.Pieces {
+				layout.Placements = append(layout.Placements, jigsaw.Placement{Index: p.Index, Position: image.Pt(i*1000, 1000)})
+			}
+			g, err := jigsaw.NewGame(jig, layout)
+			assert.NoError(t, err)
+			solve(g)
+			assert.True(t, g.Complete(), "expected the pieces of %T to snap together", tiling)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package jigsaw
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/disintegration/imaging"
+	_ "github.com/maleck13/jigsaw/Godeps/_workspace/src/golang.org/x/image/bmp"
+	_ "github.com/maleck13/jigsaw/Godeps/_workspace/src/golang.org/x/image/tiff"
+)
+
+// IMAGE_EXTENSIONS are the file extensions of the formats LoadImage can read
+var IMAGE_EXTENSIONS = []string{".jpg", ".jpeg", ".png", ".gif", ".bmp", ".tif", ".tiff"}
+
+// the EXIF orientation tag and the values it takes, each saying how the stored image has to be turned to
+// be the right way up
+const (
+	exifOrientationTag = 0x0112
+	orientNormal       = 1
+	orientFlipH        = 2
+	orientRotate180    = 3
+	orientFlipV        = 4
+	orientTranspose    = 5
+	orientRotate90     = 6
+	orientTransverse   = 7
+	orientRotate270    = 8
+)
+
+// LoadImage reads and decodes the image file at path, see DecodeImage
+func LoadImage(path string) (image.Image, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := DecodeImage(f)
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s: %s", path, err)
+	}
+	return img, nil
+}
+
+// DecodeImage decodes a JPEG, PNG, GIF, BMP or TIFF image, whichever it turns out to be, and returns it
+// with the name of the format. Photos that carry an EXIF orientation, as phone photos do, are turned the
+// right way up so they are not cut sideways
+func DecodeImage(r io.Reader) (image.Image, string, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, "", err
+	}
+	img, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, "", err
+	}
+	return orient(img, exifOrientation(data)), format, nil
+}
+
+// IsImageFile reports whether the file has one of the IMAGE_EXTENSIONS
+func IsImageFile(path string) bool {
+	ext := strings.ToLower(filepath.Ext(path))
+	for _, e := range IMAGE_EXTENSIONS {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+func orient(img image.Image, orientation int) image.Image {
+	//imaging rotates counter clockwise
+	switch orientation {
+	case orientFlipH:
+		return imaging.FlipH(img)
+	case orientRotate180:
+		return imaging.Rotate180(img)
+	case orientFlipV:
+		return imaging.FlipV(img)
+	case orientTranspose:
+		return imaging.Transpose(img)
+	case orientRotate90:
+		return imaging.Rotate270(img)
+	case orientTransverse:
+		return imaging.Transverse(img)
+	case orientRotate270:
+		return imaging.Rotate90(img)
+	}
+	return img
+}
+
+// exifOrientation finds the orientation of a JPEG or TIFF image, anything without one or that can not be
+// read is treated as the right way up
+func exifOrientation(data []byte) int {
+	if len(data) >= 4 && (string(data[:4]) == "II*\x00" || string(data[:4]) == "MM\x00*") {
+		return tiffOrientation(data)
+	}
+	if len(data) < 2 || data[0] != 0xff || data[1] != 0xd8 {
+		return orientNormal
+	}
+	//walk the jpeg segments looking for the APP1 one holding the exif data
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xff {
+			return orientNormal
+		}
+		marker := data[i+1]
+		if marker == 0xd8 || (marker >= 0xd0 && marker <= 0xd7) || marker == 0x01 || marker == 0xff {
+			i++
+			continue
+		}
+		if marker == 0xda || marker == 0xd9 {
+			//the image data has started, the exif data always comes before it
+			return orientNormal
+		}
+		length := int(binary.BigEndian.Uint16(data[i+2:]))
+		if length < 2 || i+2+length > len(data) {
+			return orientNormal
+		}
+		segment := data[i+4 : i+2+length]
+		if marker == 0xe1 && bytes.HasPrefix(segment, []byte("Exif\x00\x00")) {
+			return tiffOrientation(segment[6:])
+		}
+		i += 2 + length
+	}
+	return orientNormal
+}
+
+// tiffOrientation reads the orientation tag from the first directory of TIFF structured data
+func tiffOrientation(data []byte) int {
+	if len(data) < 8 {
+		return orientNormal
+	}
+	var order binary.ByteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return orientNormal
+	}
+	ifd := int(order.Uint32(data[4:]))
+	if ifd < 8 || ifd+2 > len(data) {
+		return orientNormal
+	}
+	entries := int(order.Uint16(data[ifd:]))
+	for e := 0; e < entries; e++ {
+		at := ifd + 2 + e*12
+		if at+12 > len(data) {
+			break
+		}
+		if order.Uint16(data[at:]) != exifOrientationTag {
+			continue
+		}
+		if v := int(order.Uint16(data[at+8:])); v >= orientNormal && v <= orientRotate270 {
+			return v
+		}
+		break
+	}
+	return orientNormal
+}
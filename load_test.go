@@ -0,0 +1,85 @@
+package jigsaw_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/jpeg"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/golang.org/x/image/bmp"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/golang.org/x/image/tiff"
+)
+
+// halves is 40x20, red on the left and blue on the right
+func halves() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 40, 20))
+	draw.Draw(img, image.Rect(0, 0, 20, 20), image.NewUniform(color.RGBA{255, 0, 0, 255}), image.ZP, draw.Src)
+	draw.Draw(img, image.Rect(20, 0, 40, 20), image.NewUniform(color.RGBA{0, 0, 255, 255}), image.ZP, draw.Src)
+	return img
+}
+
+func isRed(c color.Color) bool {
+	r, _, b, _ := c.RGBA()
+	return r > 0xc000 && b < 0x4000
+}
+
+// withOrientation adds an exif segment holding the orientation straight after the start of the jpeg
+func withOrientation(t *testing.T, orientation uint16) []byte {
+	buf := &bytes.Buffer{}
+	assert.NoError(t, jpeg.Encode(buf, halves(), &jpeg.Options{Quality: 95}))
+	exif := &bytes.Buffer{}
+	exif.WriteString("Exif\x00\x00MM\x00*")
+	//one directory straight after the header with a single SHORT entry
+	for _, v := range []interface{}{uint32(8), uint16(1), uint16(0x0112), uint16(3), uint32(1), orientation, uint16(0), uint32(0)} {
+		binary.Write(exif, binary.BigEndian, v)
+	}
+	segment := &bytes.Buffer{}
+	segment.Write([]byte{0xff, 0xe1})
+	binary.Write(segment, binary.BigEndian, uint16(exif.Len()+2))
+	segment.Write(exif.Bytes())
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), segment.Bytes()...), data[2:]...)
+}
+
+func TestDecodeImageFormats(t *testing.T) {
+	for format, encode := range map[string]func(*bytes.Buffer) error{
+		"bmp":  func(b *bytes.Buffer) error { return bmp.Encode(b, halves()) },
+		"tiff": func(b *bytes.Buffer) error { return tiff.Encode(b, halves(), nil) },
+		"jpeg": func(b *bytes.Buffer) error { return jpeg.Encode(b, halves(), nil) },
+	} {
+		buf := &bytes.Buffer{}
+		assert.NoError(t, encode(buf))
+		img, got, err := jigsaw.DecodeImage(buf)
+		if assert.NoError(t, err, "did not expect an error decoding %s", format) {
+			assert.Equal(t, format, got)
+			assert.Equal(t, image.Pt(40, 20), img.Bounds().Size())
+		}
+	}
+	_, _, err := jigsaw.DecodeImage(bytes.NewReader([]byte("not an image")))
+	assert.Error(t, err)
+}
+
+func TestDecodeImageHonoursExifOrientation(t *testing.T) {
+	//6 means the camera was turned clockwise, so the left of the stored image is the top
+	img, _, err := jigsaw.DecodeImage(bytes.NewReader(withOrientation(t, 6)))
+	assert.NoError(t, err, "did not expect an error decoding")
+	assert.Equal(t, image.Pt(20, 40), img.Bounds().Size())
+	assert.True(t, isRed(img.At(10, 5)), "expected red at the top")
+	assert.False(t, isRed(img.At(10, 35)), "expected blue at the bottom")
+
+	//8 turns it the other way
+	img, _, err = jigsaw.DecodeImage(bytes.NewReader(withOrientation(t, 8)))
+	assert.NoError(t, err, "did not expect an error decoding")
+	assert.True(t, isRed(img.At(10, 35)), "expected red at the bottom")
+
+	//3 turns it upside down
+	img, _, err = jigsaw.DecodeImage(bytes.NewReader(withOrientation(t, 3)))
+	assert.NoError(t, err, "did not expect an error decoding")
+	assert.Equal(t, image.Pt(40, 20), img.Bounds().Size())
+	assert.True(t, isRed(img.At(35, 10)), "expected red on the right")
+}
@@ -0,0 +1,108 @@
+package jigsaw
+
+import "image"
+
+// Manifest describes a cut jigsaw without its images so it can be stored and sent as json alongside the
+// piece images
+type Manifest struct {
+	Rows   int             `json:"rows"`
+	Cols   int             `json:"cols"`
+	Seed   int64           `json:"seed"`
+	Bounds image.Rectangle `json:"bounds"`
+	Fit    *FitTransform   `json:"fit,omitempty"`
+	Mode   string          `json:"mode,omitempty"`
+	Pieces []PieceManifest `json:"pieces"`
+}
+
+// PieceManifest describes a single piece. Bounds is where the piece image sits on the board when solved
+// and Atlas, when the pieces are packed into one image, where the piece is in that image. Outline is the
+// shape of a piece from a Tiling on the board. BackPath and BackAtlas are where the back of a double sided
+// piece is. Padding is how far the image reaches past Bounds when the piece has a shadow
+type PieceManifest struct {
+	Index            int              `json:"index"`
+	Name             string           `json:"name"`
+	Path             string           `json:"path,omitempty"`
+	Row              int              `json:"row"`
+	Col              int              `json:"col"`
+	Rotation         int              `json:"rotation"`
+	Bounds           image.Rectangle  `json:"bounds"`
+	Joints           []PieceJoint     `json:"joints"`
+	IsCorner         bool             `json:"isCorner"`
+	IsEdge           bool             `json:"isEdge"`
+	IsCenter         bool             `json:"isCenter"`
+	IsWhimsy         bool             `json:"isWhimsy,omitempty"`
+	TopPieceIndex    int              `json:"topPieceIndex,omitempty"`
+	RightPieceIndex  int              `json:"rightPieceIndex,omitempty"`
+	BottomPieceIndex int              `json:"bottomPieceIndex,omitempty"`
+	LeftPieceIndex   int              `json:"leftPieceIndex,omitempty"`
+	Outline          []image.Point    `json:"outline,omitempty"`
+	Atlas            *image.Rectangle `json:"atlas,omitempty"`
+	BackPath         string           `json:"backPath,omitempty"`
+	BackAtlas        *image.Rectangle `json:"backAtlas,omitempty"`
+	Padding          *Padding         `json:"padding,omitempty"`
+}
+
+// Manifest describes the jigsaw
+func (j Jigsaw) Manifest() Manifest {
+	m := Manifest{Rows: j.Rows, Cols: j.Cols, Seed: j.Seed, Bounds: j.Bounds, Fit: j.Fit, Mode: j.Mode, Pieces: make([]PieceManifest, len(j.Pieces))}
+	for i, p := range j.Pieces {
+		m.Pieces[i] = PieceManifest{
+			Index:            p.Index,
+			Name:             p.Name,
+			Path:             p.Path,
+			Row:              p.Row,
+			Col:              p.Col,
+			Rotation:         p.Rotation,
+			Bounds:           p.Bounds,
+			Joints:           p.Joints,
+			IsCorner:         p.IsCorner,
+			IsEdge:           p.IsEdge,
+			IsCenter:         p.IsCenter,
+			IsWhimsy:         p.IsWhimsy,
+			TopPieceIndex:    p.TopPieceIndex,
+			RightPieceIndex:  p.RightPieceIndex,
+			BottomPieceIndex: p.BottomPieceIndex,
+			LeftPieceIndex:   p.LeftPieceIndex,
+			Outline:          p.Outline,
+			BackPath:         p.BackPath,
+		}
+		if p.Padding != (Padding{}) {
+			padding := p.Padding
+			m.Pieces[i].Padding = &padding
+		}
+	}
+	return m
+}
+
+// Jigsaw rebuilds the jigsaw the manifest describes. The pieces have no images, which is enough to play
+// a game of it
+func (m Manifest) Jigsaw() Jigsaw {
+	j := Jigsaw{Rows: m.Rows, Cols: m.Cols, Seed: m.Seed, Bounds: m.Bounds, Fit: m.Fit, Mode: m.Mode, Pieces: make([]*Piece, len(m.Pieces))}
+	for i, p := range m.Pieces {
+		j.Pieces[i] = &Piece{
+			Index:            p.Index,
+			Name:             p.Name,
+			Path:             p.Path,
+			Row:              p.Row,
+			Col:              p.Col,
+			Rotation:         p.Rotation,
+			Bounds:           p.Bounds,
+			Joints:           p.Joints,
+			IsCorner:         p.IsCorner,
+			IsEdge:           p.IsEdge,
+			IsCenter:         p.IsCenter,
+			IsWhimsy:         p.IsWhimsy,
+			TopPieceIndex:    p.TopPieceIndex,
+			RightPieceIndex:  p.RightPieceIndex,
+			BottomPieceIndex: p.BottomPieceIndex,
+			LeftPieceIndex:   p.LeftPieceIndex,
+			Outline:          p.Outline,
+			BackPath:         p.BackPath,
+			Board:            m.Bounds,
+		}
+		if p.Padding != nil {
+			j.Pieces[i].Padding = *p.Padding
+		}
+	}
+	return j
+}
@@ -0,0 +1,106 @@
+package jigsaw
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// the modes a jigsaw can be cut in, all of its pieces, only those around its edge for a frame to build
+// first or only those in its centre to fill the frame in later
+const (
+	MODE_ALL    = "all"
+	MODE_EDGE   = "edge"
+	MODE_CENTER = "center"
+)
+
+var ErrModeDisconnected = errors.New("the pieces kept by the mode are not all joined to each other")
+
+func (jb *JigsawBuilder) validateMode() error {
+	switch jb.Mode {
+	case "", MODE_ALL, MODE_EDGE, MODE_CENTER:
+		return nil
+	}
+	return fmt.Errorf("unknown mode %q", jb.Mode)
+}
+
+// keepPieces drops the pieces the mode leaves out, once the whole jigsaw has been cut so the pieces that
+// are kept are the same as in the whole jigsaw. Tilings and whimsies can leave pieces that do not join any
+// of the others, which could never be put together, so that is ErrModeDisconnected. The images of the pieces
+// that are dropped are removed if they have been saved and the pieces that are kept forget they had them as
+// neighbours
+func keepPieces(pieces []*Piece, mode string) ([]*Piece, error) {
+	if mode != MODE_EDGE && mode != MODE_CENTER {
+		return pieces, nil
+	}
+	kept := make([]*Piece, 0, len(pieces))
+	dropped := make([]*Piece, 0, len(pieces))
+	for _, p := range pieces {
+		if (mode == MODE_EDGE && p.IsEdge) || (mode == MODE_CENTER && p.IsCenter) {
+			kept = append(kept, p)
+		} else {
+			dropped = append(dropped, p)
+		}
+	}
+	if len(kept) == 0 {
+		return nil, fmt.Errorf("the jigsaw has no %s pieces", mode)
+	}
+	if !connected(kept) {
+		return nil, ErrModeDisconnected
+	}
+	for _, p := range dropped {
+		for _, path := range []string{p.Path, p.BackPath} {
+			if path == "" {
+				continue
+			}
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return nil, err
+			}
+		}
+	}
+	forgetDropped(kept)
+	return kept, nil
+}
+
+// connected reports whether every one of the pieces can be reached from the first through neighbours that
+// are also among them
+func connected(pieces []*Piece) bool {
+	byIndex := make(map[int]*Piece, len(pieces))
+	for _, p := range pieces {
+		byIndex[p.Index] = p
+	}
+	seen := map[int]bool{pieces[0].Index: true}
+	queue := []*Piece{pieces[0]}
+	for len(queue) > 0 {
+		p := queue[0]
+		queue = queue[1:]
+		for _, n := range p.Neighbours() {
+			if other, ok := byIndex[n]; ok && !seen[n] {
+				seen[n] = true
+				queue = append(queue, other)
+			}
+		}
+	}
+	return len(seen) == len(pieces)
+}
+
+// forgetDropped clears every neighbour index that is not one of the pieces, the sides they were on are left
+// as they were cut but no longer join onto anything
+func forgetDropped(pieces []*Piece) {
+	indexes := make(map[int]bool, len(pieces))
+	for _, p := range pieces {
+		indexes[p.Index] = true
+	}
+	for _, p := range pieces {
+		for _, i := range []*int{&p.TopPieceIndex, &p.RightPieceIndex, &p.BottomPieceIndex, &p.LeftPieceIndex} {
+			if !indexes[*i] {
+				*i = 0
+			}
+		}
+		for j := range p.Joints {
+			if !indexes[p.Joints[j].Neighbour] {
+				p.Joints[j].Neighbour = 0
+			}
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package jigsaw_test
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func modeJigsaw(t *testing.T, mode string) jigsaw.Jigsaw {
+	return buildJigsaw(t, filled(160, 160, color.White), 16, jigsaw.JigsawPieceCutter{}, func(b *jigsaw.JigsawBuilder) {
+		b.Seed = 9
+		b.Mode = mode
+	})
+}
+
+func indexes(jig jigsaw.Jigsaw) []int {
+	var is []int
+	for _, p := range jig.Pieces {
+		is = append(is, p.Index)
+	}
+	return is
+}
+
+func TestModeEdgeKeepsTheFrame(t *testing.T) {
+	all, edge := modeJigsaw(t, jigsaw.MODE_ALL), modeJigsaw(t, jigsaw.MODE_EDGE)
+	assert.Equal(t, []int{1, 2, 3, 4, 5, 8, 9, 12, 13, 14, 15, 16}, indexes(edge))
+	assert.Equal(t, jigsaw.MODE_EDGE, edge.Mode)
+	assert.Equal(t, "", all.Mode)
+	for _, p := range edge.Pieces {
+		whole := all.Pieces[p.Index-1]
+		assert.Equal(t, whole.Bounds, p.Bounds, "expected piece %d to be cut as in the whole jigsaw", p.Index)
+		assert.Equal(t, len(whole.Joints), len(p.Joints))
+		for i, j := range p.Joints {
+			if j.Neighbour == 0 {
+				j.Neighbour = whole.Joints[i].Neighbour
+			}
+			assert.Equal(t, whole.Joints[i], j, "expected piece %d to keep the shape of its sides", p.Index)
+		}
+	}
+
+	m := edge.Manifest()
+	assert.Equal(t, jigsaw.MODE_EDGE, m.Mode)
+	assert.Len(t, m.Pieces, 12)
+	assert.Equal(t, edge.Mode, m.Jigsaw().Mode)
+}
+
+func TestModeForgetsDroppedNeighbours(t *testing.T) {
+	for _, mode := range []string{jigsaw.MODE_EDGE, jigsaw.MODE_CENTER} {
+		m := modeJigsaw(t, mode).Manifest()
+		kept := make(map[int]bool)
+		for _, p := range m.Pieces {
+			kept[p.Index] = true
+		}
+		joined := 0
+		for _, p := range m.Pieces {
+			for _, i := range []int{p.TopPieceIndex, p.RightPieceIndex, p.BottomPieceIndex, p.LeftPieceIndex} {
+				assert.True(t, i == 0 || kept[i], "expected %s piece %d not to point at dropped piece %d", mode, p.Index, i)
+			}
+			for _, j := range p.Joints {
+				assert.True(t, j.Neighbour == 0 || kept[j.Neighbour], "expected %s piece %d not to join dropped piece %d", mode, p.Index, j.Neighbour)
+				if j.Neighbour != 0 {
+					joined++
+				}
+			}
+		}
+		assert.True(t, joined > 0, "expected the %s pieces to still join each other", mode)
+	}
+}
+
+func TestModeCenterKeepsTheRest(t *testing.T) {
+	center := modeJigsaw(t, jigsaw.MODE_CENTER)
+	assert.Equal(t, []int{6, 7, 10, 11}, indexes(center))
+	for _, p := range center.Pieces {
+		assert.True(t, p.IsCenter)
+	}
+}
+
+func TestModeGamesSolve(t *testing.T) {
+	for _, mode := range []string{jigsaw.MODE_EDGE, jigsaw.MODE_CENTER} {
+		jig := modeJigsaw(t, mode)
+		layout := &jigsaw.Layout{}
+		for i, p := range jig.Pieces {
+			layout.Placements = append(layout.Placements, jigsaw.Placement{Index: p.Index, Position: image.Pt(i*1000, 1000)})
+		}
+		g, err := jigsaw.NewGame(jig, layout)
+		assert.NoError(t, err)
+		solve(g)
+		assert.True(t, g.Complete(), "expected the %s pieces to snap together", mode)
+	}
+}
+
+func TestModeRemovesDroppedPieces(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mode")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(filled(160, 160, color.White), 16, jigsaw.JigsawPieceCutter{OutputDir: dir})
+	builder.Mode = jigsaw.MODE_CENTER
+	_, err = builder.Build()
+	assert.NoError(t, err)
+	files, err := filepath.Glob(filepath.Join(dir, "*.png"))
+	assert.NoError(t, err)
+	assert.Len(t, files, 4, "expected only the center pieces to be saved")
+}
+
+func TestModeErrors(t *testing.T) {
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(filled(100, 100, color.White), 4, jigsaw.JigsawPieceCutter{})
+	builder.Mode = jigsaw.MODE_CENTER
+	_, err := builder.Build()
+	assert.Error(t, err, "expected an error when no piece is in the centre")
+
+	//a wide board leaves edge pieces reaching from top to bottom between the centre ones
+	builder = jigsaw.NewJigsawBuilderWithPieceCutter(filled(480, 120, color.White), 16, jigsaw.JigsawPieceCutter{})
+	builder.Mode = jigsaw.MODE_CENTER
+	builder.Tiling = jigsaw.VoronoiTiling{}
+	builder.Seed = 6
+	_, err = builder.Build()
+	assert.Equal(t, jigsaw.ErrModeDisconnected, err)
+
+	builder = jigsaw.NewJigsawBuilderWithPieceCutter(filled(100, 100, color.White), 4, jigsaw.JigsawPieceCutter{})
+	builder.Mode = "middle"
+	_, err = builder.Build()
+	assert.Error(t, err)
+}
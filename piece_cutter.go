@@ -3,14 +3,21 @@ package jigsaw
 import (
 	"errors"
 	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"path/filepath"
+
 	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/disintegration/imaging"
 	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/llgcode/draw2d/draw2dimg"
 	"github.com/maleck13/jigsaw/Godeps/_workspace/src/golang.org/x/image/draw"
-	"image"
 )
 
 const PERCENTAGE = 10.0
 
+// DEFAULT_OUTPUT_DIR is where NewJigsawBuilder saves the cut pieces
+const DEFAULT_OUTPUT_DIR = "./out"
+
 type PieceCutter interface {
 	CutPieces(from image.Image, pieces []*Piece) ([]*Piece, error)
 }
@@ -31,120 +38,93 @@ func Percentage(piece *Piece, percentage float64) int {
 	return int((percentage / 100.0) * float64(piece.Width))
 }
 
-type JigsawPieceCutter struct{}
+// the shapes a joint can be cut in
+const (
+	JOINT_ROUND  = "round"
+	JOINT_SQUARE = "square"
+	JOINT_FLAT   = "flat"
+)
+
+// MAX_TAB_SIZE is the largest TabSize that still leaves room for the tabs of neighbouring sides
+const MAX_TAB_SIZE = 25.0
+
+// JigsawPieceCutter cuts the pieces out of the image. When OutputDir is set each shaped piece is also
+// saved there as a png and its Path set, otherwise the pieces are only kept in memory.
+// TabSize is how far a tab sticks out as a percentage of the longer side of a piece, 0 uses PERCENTAGE.
+// JointStyle is one of the JOINT_ shapes, empty is JOINT_ROUND and JOINT_FLAT cuts plain rectangles
+type JigsawPieceCutter struct {
+	OutputDir  string
+	TabSize    float64
+	JointStyle string
+}
+
+func (jpc JigsawPieceCutter) tabSize() float64 {
+	if jpc.JointStyle == JOINT_FLAT {
+		return 0
+	}
+	if jpc.TabSize == 0 {
+		return PERCENTAGE
+	}
+	return jpc.TabSize
+}
+
+// Validate checks the TabSize and JointStyle, CutPieces fails with the same error
+func (jpc JigsawPieceCutter) Validate() error {
+	switch jpc.JointStyle {
+	case "", JOINT_ROUND, JOINT_SQUARE, JOINT_FLAT:
+	default:
+		return fmt.Errorf("unknown joint style %q", jpc.JointStyle)
+	}
+	if jpc.TabSize < 0 || jpc.TabSize > MAX_TAB_SIZE {
+		return fmt.Errorf("tab size should be between 0 and %v percent", MAX_TAB_SIZE)
+	}
+	return nil
+}
+
 type JigsawPieceMarker struct{}
 
+// every shared edge gets exactly one tab and one blank. The piece to the left of an edge
+// and the piece above an edge carry the tab (external joint), the other piece the blank.
+// No joints are added on the sides that lie on the border of the board. Pieces from a Tiling
+// already have a joint on each shared side and the one with the lower Index carries the tab
 func (JigsawPieceMarker) MarkPieces(pieces []*Piece, piecesPerRow, numRows int) []*Piece {
-	//start at 0 move across
 	retPieces := make([]*Piece, 0)
 	for i := 0; i < len(pieces); i++ {
 		p := pieces[i]
-		if p.IsCorner {
-			if i == 0 {
-				//right corner
-				fmt.Println("top left corner", p.Name)
-				p.Joints = append(p.Joints, PieceJoint{
-					Side:     RIGHT_SIDE,
-					External: true,
-				}, PieceJoint{
-					Side:     BOTTOM_SIDE,
-					External: true,
-				})
-			} else if p.Index == piecesPerRow {
-				fmt.Println("top right corner", p.Name)
-				p.Joints = append(p.Joints, PieceJoint{
-					Side:     LEFT_SIDE,
-					External: false,
-				}, PieceJoint{
-					Side:     BOTTOM_SIDE,
-					External: true,
-				})
-				//left corner
-			} else if p.Index%piecesPerRow == 1 {
-				fmt.Println("bottom left corner", p.Name)
-				//bottom left
-				p.Joints = append(p.Joints, PieceJoint{
-					Side:     RIGHT_SIDE,
-					External: true,
-				}, PieceJoint{
-					Side:     TOP_SIDE,
-					External: false,
-				})
-			} else if p.Index == (piecesPerRow * numRows) {
-				fmt.Println("bottom right corner", p.Name)
-				//bottom right
-				p.Joints = append(p.Joints, PieceJoint{
-					Side:     LEFT_SIDE,
-					External: false,
-				}, PieceJoint{
-					Side:     TOP_SIDE,
-					External: false,
-				})
-			}
-		} else {
-			if p.FarLeftVerticalRow() {
-				//its not a corner and it on the left edge
-				p.Joints = append(p.Joints, PieceJoint{
-					Side:     RIGHT_SIDE,
-					External: true,
-				}, PieceJoint{
-					Side:     TOP_SIDE,
-					External: false,
-				}, PieceJoint{
-					Side:     BOTTOM_SIDE,
-					External: true,
-				})
-
-			} else if p.FarRightVerticalRow() {
-				//TODO these pieces are slightly off for some reason
-				//its not a corner and it on the right edge
-				p.Joints = append(p.Joints, PieceJoint{
-					Side:     LEFT_SIDE,
-					External: false,
-				}, PieceJoint{
-					Side:     TOP_SIDE,
-					External: false,
-				}, PieceJoint{
-					Side:     BOTTOM_SIDE,
-					External: true,
-				})
-			} else if p.BottomRow() {
-				p.Joints = append(p.Joints, PieceJoint{
-					Side:     RIGHT_SIDE,
-					External: true,
-				}, PieceJoint{
-					Side:     TOP_SIDE,
-					External: true,
-				}, PieceJoint{
-					Side:     LEFT_SIDE,
-					External: false,
-				})
-			} else if p.TopRow() {
-				p.Joints = append(p.Joints, PieceJoint{
-					Side:     RIGHT_SIDE,
-					External: true,
-				}, PieceJoint{
-					Side:     BOTTOM_SIDE,
-					External: true,
-				}, PieceJoint{
-					Side:     LEFT_SIDE,
-					External: false,
-				})
-			} else {
-				p.Joints = append(p.Joints, PieceJoint{
-					Side:     RIGHT_SIDE,
-					External: true,
-				}, PieceJoint{
-					Side:     BOTTOM_SIDE,
-					External: false,
-				}, PieceJoint{
-					Side:     LEFT_SIDE,
-					External: false,
-				}, PieceJoint{
-					Side:     TOP_SIDE,
-					External: false,
-				})
+		if p.Outline != nil {
+			for j := range p.Joints {
+				p.Joints[j].External = p.Index < p.Joints[j].Neighbour
 			}
+			retPieces = append(retPieces, p)
+			continue
+		}
+		if p.Col < piecesPerRow-1 {
+			p.Joints = append(p.Joints, PieceJoint{
+				Side:      RIGHT_SIDE,
+				External:  true,
+				Neighbour: p.RightPieceIndex,
+			})
+		}
+		if p.Row < numRows-1 {
+			p.Joints = append(p.Joints, PieceJoint{
+				Side:      BOTTOM_SIDE,
+				External:  true,
+				Neighbour: p.BottomPieceIndex,
+			})
+		}
+		if p.Col > 0 {
+			p.Joints = append(p.Joints, PieceJoint{
+				Side:      LEFT_SIDE,
+				External:  false,
+				Neighbour: p.LeftPieceIndex,
+			})
+		}
+		if p.Row > 0 {
+			p.Joints = append(p.Joints, PieceJoint{
+				Side:      TOP_SIDE,
+				External:  false,
+				Neighbour: p.TopPieceIndex,
+			})
 		}
 		retPieces = append(retPieces, p)
 	}
@@ -152,180 +132,205 @@ func (JigsawPieceMarker) MarkPieces(pieces []*Piece, piecesPerRow, numRows int)
 	return retPieces
 }
 
-//cuts a rectangular piece with additional space added for any external joints
-func (JigsawPieceCutter) cutPiece(from image.Image, piece *Piece) (*Piece, error) {
-	//cut the piece 20%larger on the sides that have external joints
-
+// cuts a rectangular piece with additional space added for the depth of any external joints and for
+// any wavy edge bulging out of the piece
+func (jpc JigsawPieceCutter) cutPiece(from image.Image, piece *Piece) (*Piece, error) {
 	for _, joint := range piece.Joints {
-		if joint.External {
-			if joint.Side == TOP_SIDE {
-				// 0 is top side  (positive)
-				//grow point[0].Y unless it already at max
-				piece.Points[0].Y -= Percentage(piece, PERCENTAGE)
-			} else if joint.Side == RIGHT_SIDE {
-				//1 is right side
-				//grow point[3].X (positive)unless it already at max
-				piece.Points[3].X += Percentage(piece, PERCENTAGE)
-			} else if joint.Side == BOTTOM_SIDE {
-				//2 is bottom side
-				//grow point[3].Y (by a negative value)	 unless it already at min
-				fmt.Println("Y is ", piece.Points[3].Y)
-				piece.Points[3].Y += Percentage(piece, PERCENTAGE)
-				fmt.Println("Y is ", piece.Points[3].Y)
-			} else if joint.Side == LEFT_SIDE {
-				//3 is left side
-				//grow point[0].X (negative)
-				piece.Points[0].X -= Percentage(piece, PERCENTAGE)
-			}
+		//non external joints are cut into the existing piece unless the edge waves out of it
+		depth := waveDepth(joint, piece)
+		if tab := Percentage(piece, jpc.jointSize(joint)); joint.External && tab > depth {
+			depth = tab
+		}
+		if joint.Side == TOP_SIDE {
+			// 0 is top side grow point[0].Y up
+			piece.Points[0].Y -= depth
+		} else if joint.Side == RIGHT_SIDE {
+			//1 is right side grow point[3].X right
+			piece.Points[3].X += depth
+		} else if joint.Side == BOTTOM_SIDE {
+			//2 is bottom side grow point[3].Y down
+			piece.Points[3].Y += depth
+		} else if joint.Side == LEFT_SIDE {
+			//3 is left side grow point[0].X left
+			piece.Points[0].X -= depth
 		}
-		//non external joints are cut into the existing piece
 	}
 	piece.Bounds = image.Rect(piece.Points[0].X, piece.Points[0].Y, piece.Points[3].X, piece.Points[3].Y)
-	//fmt.Println("BOUNDS CREATED as ", piece.Bounds)
 	rectCropImg := imaging.Crop(from, piece.Bounds)
 	piece.Image = rectCropImg
-	path := "./out/" + piece.Name + ".png"
-	// save cropped image
-	err := imaging.Save(rectCropImg, path)
-	piece.Path = path
-	return piece, err
-}
-
-func variableRadius(basedOn *Piece) int {
-	return Percentage(basedOn, PERCENTAGE)
+	return piece, nil
 }
 
-func hasExternalJoint(piece *Piece, side int) bool {
-	for _, j := range piece.Joints {
-		if j.Side == side {
-			return j.External
-		}
+// jointSize is the size of the joint's tab, joints without their own Size use the cutter's
+func (jpc JigsawPieceCutter) jointSize(joint PieceJoint) float64 {
+	if jpc.JointStyle == JOINT_FLAT {
+		return 0
+	}
+	if joint.Size > 0 {
+		return joint.Size
 	}
-	return false
+	return jpc.tabSize()
 }
 
+// JointCutter cuts the joints of a piece, Tab is the size of tabs without their own Size as a
+// percentage like JigsawPieceCutter.TabSize and Square cuts square tabs instead of round ones
 type JointCutter struct {
-	Piece *Piece
+	Piece  *Piece
+	Tab    float64
+	Square bool
+}
+
+// cell is where the piece sat on the board before any tabs were added, in the coordinates of its image
+func (jc JointCutter) cell(img image.Image) image.Rectangle {
+	p := jc.Piece
+	return p.gridCell().Sub(p.Bounds.Min).Add(img.Bounds().Min)
+}
+
+// centre is where on the edge of the cell the joint's tab is centred and radius how deep it is. The tab is
+// moved along the edge by its Offset but never so far that it runs off the end of the edge
+func (jc JointCutter) centre(joint PieceJoint, cell image.Rectangle) (image.Point, int) {
+	size := joint.Size
+	if size <= 0 {
+		size = jc.Tab
+	}
+	radius := Percentage(jc.Piece, size)
+	length := edgeLength(joint, cell)
+	shift := int(joint.Offset * float64(length))
+	if limit := length/2 - radius; limit <= 0 {
+		shift = 0
+	} else if shift > limit {
+		shift = limit
+	} else if shift < -limit {
+		shift = -limit
+	}
+	mid := cell.Min.Add(cell.Size().Div(2))
+	switch joint.Side {
+	case TOP_SIDE:
+		return image.Pt(mid.X+shift, cell.Min.Y), radius
+	case RIGHT_SIDE:
+		return image.Pt(cell.Max.X, mid.Y+shift), radius
+	case BOTTOM_SIDE:
+		return image.Pt(mid.X+shift, cell.Max.Y), radius
+	}
+	return image.Pt(cell.Min.X, mid.Y+shift), radius
 }
 
+// cutInternal cuts the blank out of the piece along with anything on the far side of a wavy edge
 func (jc JointCutter) cutInternal(joint PieceJoint, img image.Image) (image.Image, error) {
-	var radius, x, y int
-	//todo dry it up
-	//todo when there is more than one middle row it is not cutting pieces correctly
-
-	if joint.Side == TOP_SIDE {
-		radius = Percentage(jc.Piece, PERCENTAGE)
-		x, y = img.Bounds().Max.X/2, img.Bounds().Min.Y
-	} else if joint.Side == RIGHT_SIDE {
-		radius = Percentage(jc.Piece, PERCENTAGE)
-		percentInc := 0.0
-		if hasExternalJoint(jc.Piece, 0) {
-			percentInc += PERCENTAGE
-			x, y = img.Bounds().Max.X, (img.Bounds().Max.Y+Percentage(jc.Piece, percentInc))/2
-		}else if hasExternalJoint(jc.Piece, 2) {
-			percentInc += PERCENTAGE
-			x, y = img.Bounds().Max.X, (img.Bounds().Max.Y-Percentage(jc.Piece, percentInc))/2
-		}else{
-			x, y = img.Bounds().Max.X, img.Bounds().Max.Y /2
-		}
+	return jc.cut(joint, img), nil
+}
 
-	} else if joint.Side == BOTTOM_SIDE {
-		radius = Percentage(jc.Piece, PERCENTAGE)
-		x, y = img.Bounds().Max.X/2, img.Bounds().Max.Y
-	} else if joint.Side == LEFT_SIDE {
-		radius = Percentage(jc.Piece, PERCENTAGE)
-		percentInc := 0.0
-		if hasExternalJoint(jc.Piece, 0) {
-			percentInc += PERCENTAGE
-			x, y = img.Bounds().Min.X, (img.Bounds().Max.Y+Percentage(jc.Piece, percentInc))/2
-		}else if hasExternalJoint(jc.Piece, 2) {
-			percentInc += PERCENTAGE
-			x, y = img.Bounds().Min.X, (img.Bounds().Max.Y-Percentage(jc.Piece, percentInc))/2
-		}else{
-			x, y = img.Bounds().Min.X, img.Bounds().Max.Y /2
-		}
+// cutExternal clears the padding beyond the edge of the cell apart from the tab itself
+func (jc JointCutter) cutExternal(joint PieceJoint, from image.Image) (image.Image, error) {
+	return jc.cut(joint, from), nil
+}
 
+func (jc JointCutter) cut(joint PieceJoint, img image.Image) image.Image {
+	cell := jc.cell(img)
+	centre, radius := jc.centre(joint, cell)
+	if radius == 0 && len(joint.Wave) == 0 && !joint.External {
+		return img
 	}
 	imageContext := image.NewRGBA(img.Bounds())
-	circleImg := &circle{image.Pt(x, y), radius, img, false, image.Rectangle{}}
-	draw.Draw(imageContext, img.Bounds(), circleImg, img.Bounds().Min, draw.Src)
-	draw2dimg.SaveToPngFile("./out/"+jc.Piece.Name+".png", imageContext)
-	return imageContext, nil
+	mask := &jointMask{Image: img, joint: joint, cell: cell, centre: centre, radius: radius, square: jc.Square}
+	draw.Draw(imageContext, img.Bounds(), mask, img.Bounds().Min, draw.Src)
+	return imageContext
 }
 
-func (jc JointCutter) cutExternal(joint PieceJoint, from image.Image) (image.Image, error) {
+// jointMask is the image with what belongs to the neighbour across the joint made transparent. The tab
+// belongs to the piece on the external side along with everything up to the edge, which is where the cell
+// ends moved by its wave. Both pieces work this out the same way so they always fit together
+type jointMask struct {
+	image.Image
+	joint  PieceJoint
+	cell   image.Rectangle
+	centre image.Point
+	radius int
+	square bool
+}
 
-	var x, y int
-	var piece = jc.Piece
-	var imageContext = image.NewRGBA(from.Bounds())
-	var circleImg *circle
-	var percentInc = 0.0
-	var positive = true
-
-	if joint.Side == RIGHT_SIDE || joint.Side == LEFT_SIDE {
-		if hasExternalJoint(jc.Piece, 0) && hasExternalJoint(jc.Piece, 2) {
-			percentInc = 0.0
-		} else if hasExternalJoint(jc.Piece, 0) {
-			percentInc += PERCENTAGE
-		} else if hasExternalJoint(jc.Piece, 2) {
-			percentInc += PERCENTAGE
-			positive = false
-			//move up 20%
-		}
-	} else if joint.Side == BOTTOM_SIDE || joint.Side == TOP_SIDE {
-		if hasExternalJoint(jc.Piece, 3) && hasExternalJoint(jc.Piece, 1) {
-			percentInc = 0.0
-		} else if hasExternalJoint(jc.Piece, 3) {
-			percentInc += PERCENTAGE
-		} else if hasExternalJoint(jc.Piece, 1) {
-			percentInc += PERCENTAGE
-		}
+func (m *jointMask) At(x, y int) color.Color {
+	if m.tabSide(x, y) == m.joint.External {
+		return m.Image.At(x, y)
 	}
-	if joint.Side == RIGHT_SIDE {
-		//move back Percentage amount
-		y = (from.Bounds().Max.Y+Percentage(piece, percentInc))/2
-		if ! positive{
-			y =(from.Bounds().Max.Y - Percentage(piece, percentInc))/2
+	return color.Transparent
+}
+
+// tabSide reports whether the pixel belongs to the piece with the tab
+func (m *jointMask) tabSide(x, y int) bool {
+	//measure from the middle of the pixel
+	px, py := float64(x)+0.5, float64(y)+0.5
+	xx, yy, rr := px-float64(m.centre.X), py-float64(m.centre.Y), float64(m.radius)
+	if m.square {
+		if math.Abs(xx) < rr && math.Abs(yy) < rr {
+			return true
 		}
-		x = (from.Bounds().Max.X - Percentage(piece, PERCENTAGE))
-		point := image.Pt(x, y)
-		circleImg = &circle{point, variableRadius(piece), from, true, image.Rect(point.X, from.Bounds().Max.Y, from.Bounds().Max.X, from.Bounds().Min.Y)}
+	} else if xx*xx+yy*yy < rr*rr {
+		return true
 	}
-	//left
-	if joint.Side == LEFT_SIDE {
+	//the wave moves the edge towards the piece with the blank
+	switch m.joint.Side {
+	case TOP_SIDE:
+		return py < float64(m.cell.Min.Y)+m.wave(px-float64(m.cell.Min.X), m.cell.Dx())
+	case RIGHT_SIDE:
+		return px < float64(m.cell.Max.X)+m.wave(py-float64(m.cell.Min.Y), m.cell.Dy())
+	case BOTTOM_SIDE:
+		return py < float64(m.cell.Max.Y)+m.wave(px-float64(m.cell.Min.X), m.cell.Dx())
+	}
+	return px < float64(m.cell.Min.X)+m.wave(py-float64(m.cell.Min.Y), m.cell.Dy())
+}
 
-		x, y = (from.Bounds().Min.X + Percentage(piece, PERCENTAGE)), (from.Bounds().Max.Y+Percentage(piece, percentInc))/2 //half way down the left side
-		point := image.Pt(x, y)
-		circleImg = &circle{point, variableRadius(piece), from, true, image.Rect(point.X, from.Bounds().Min.Y, from.Bounds().Min.X, from.Bounds().Max.Y)}
+func (m *jointMask) wave(along float64, length int) float64 {
+	return waveAt(m.joint.Wave, along/float64(length), length)
+}
 
+// waveAt is how far, in pixels, a wavy edge of the given length is moved at t along it, running from 0 to 1
+// from the top or left end. Each term of the wave is a sine that is zero at both ends so the corners of
+// the pieces never move
+func waveAt(wave []float64, t float64, length int) float64 {
+	if t <= 0 || t >= 1 {
+		return 0
 	}
-	//top
-	if joint.Side == TOP_SIDE {
-		x, y = from.Bounds().Max.X/2, from.Bounds().Min.Y+Percentage(piece, PERCENTAGE) //half way across top line
-		point := image.Pt(x, y)                                                         //where the circle is centered around
-		circleImg = &circle{point, variableRadius(piece), from, true, image.Rect(from.Bounds().Max.X, y, from.Bounds().Min.X, from.Bounds().Min.Y)}
+	d := 0.0
+	for k, a := range wave {
+		d += a * math.Sin(float64(k+1)*math.Pi*t)
 	}
-	//bottom
-	if joint.Side == BOTTOM_SIDE {
-		x, y = from.Bounds().Max.X/2, (from.Bounds().Max.Y - Percentage(piece, PERCENTAGE)) //half way across bottom line
-		point := image.Pt(x, y)
-		circleImg = &circle{point, variableRadius(piece), from, true, image.Rect(from.Bounds().Min.X, point.Y, from.Bounds().Max.X, from.Bounds().Max.Y)}
+	return d * float64(length)
+}
 
+// waveDepth is the furthest the joint's wave can move its edge, rounded up to whole pixels
+func waveDepth(joint PieceJoint, piece *Piece) int {
+	length := edgeLength(joint, piece.gridCell())
+	sum := 0.0
+	for _, a := range joint.Wave {
+		sum += math.Abs(a)
 	}
-	draw.Draw(imageContext, from.Bounds(), circleImg, from.Bounds().Min, draw.Src)
-	return imageContext, nil
+	return int(math.Ceil(sum * float64(length)))
+}
 
+// gridCell is where a piece on a grid sits on the board before any tabs are added. Unless the grid lines
+// have been moved, see Saliency, every cell is Width by Height
+func (p *Piece) gridCell() image.Rectangle {
+	if !p.cell.Empty() {
+		return p.cell
+	}
+	return image.Rect(p.Col*p.Width, p.Row*p.Height, (p.Col+1)*p.Width, (p.Row+1)*p.Height).Add(p.Board.Min)
+}
+
+func edgeLength(joint PieceJoint, cell image.Rectangle) int {
+	if joint.Side == TOP_SIDE || joint.Side == BOTTOM_SIDE {
+		return cell.Dx()
+	}
+	return cell.Dy()
 }
 
-//shapes the rectangular piece removing and add joint pieces
-func (JigsawPieceCutter) ShapePiece(piece *Piece) (*Piece, error) {
-	//fill a semi circle with transparency
-	//the piece is 20% larger on each side add any cuts then crop down sides without external piece
-	jointCutter := JointCutter{Piece: piece}
-	fmt.Println("cutting piece ", piece.Name, piece.Joints)
+// shapes the rectangular piece removing and add joint pieces
+func (jpc JigsawPieceCutter) ShapePiece(piece *Piece) (*Piece, error) {
+	jointCutter := JointCutter{Piece: piece, Tab: jpc.tabSize(), Square: jpc.JointStyle == JOINT_SQUARE}
 	var img = piece.Image
 	var err error
 	for _, joint := range piece.Joints {
+		joint.Size = jpc.jointSize(joint)
 		if !joint.External {
 			img, err = jointCutter.cutInternal(joint, img)
 			if err != nil {
@@ -333,14 +338,32 @@ func (JigsawPieceCutter) ShapePiece(piece *Piece) (*Piece, error) {
 			}
 		} else {
 			img, err = jointCutter.cutExternal(joint, img)
-
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
-	draw2dimg.SaveToPngFile("./out/"+piece.Name+".png", img)
+	piece.Image = img
+	if err := jpc.save(piece); err != nil {
+		return nil, err
+	}
 
 	return piece, nil
 }
 
+// save writes the piece's image to the OutputDir, when there is one
+func (jpc JigsawPieceCutter) save(piece *Piece) error {
+	if jpc.OutputDir == "" {
+		return nil
+	}
+	path := filepath.Join(jpc.OutputDir, piece.Name+".png")
+	if err := draw2dimg.SaveToPngFile(path, piece.Image); err != nil {
+		return err
+	}
+	piece.Path = path
+	return nil
+}
+
 func (jpc JigsawPieceCutter) ShapePieces(pieces []*Piece) ([]*Piece, error) {
 	shapedPieces := make([]*Piece, len(pieces))
 	for i, piece := range pieces {
@@ -354,6 +377,12 @@ func (jpc JigsawPieceCutter) ShapePieces(pieces []*Piece) ([]*Piece, error) {
 }
 
 func (jpc JigsawPieceCutter) CutPieces(from image.Image, pieces []*Piece) ([]*Piece, error) {
+	if err := jpc.Validate(); err != nil {
+		return nil, err
+	}
+	if len(pieces) > 0 && pieces[0].Outline != nil {
+		return jpc.cutTiles(from, pieces)
+	}
 	var cutPieces = make([]*Piece, len(pieces))
 	for index, p := range pieces {
 		cutPiece, err := jpc.cutPiece(from, p)
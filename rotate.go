@@ -0,0 +1,68 @@
+package jigsaw
+
+import (
+	"math/rand"
+
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/disintegration/imaging"
+)
+
+// RotateSide returns where side ends up once a piece is turned clockwise by degrees
+func RotateSide(side, degrees int) int {
+	return (side + degrees/90) % 4
+}
+
+// Rotate turns the piece clockwise by a multiple of 90 degrees. The image is rotated and the joints are
+// moved to the side they now face so the piece still describes itself correctly. The back, seen from the
+// other side, turns the other way, and the padding turns with the image. Pieces from a Tiling
+// number their sides by their Outline, which stays where it is on the board, so their joints are left alone
+func (p *Piece) Rotate(degrees int) error {
+	degrees = ((degrees % 360) + 360) % 360
+	if degrees%90 != 0 {
+		return ErrBadRotation
+	}
+	if degrees == 0 {
+		return nil
+	}
+	if p.Image != nil {
+		//imaging rotates counter clockwise
+		switch degrees {
+		case 90:
+			p.Image = imaging.Rotate270(p.Image)
+		case 180:
+			p.Image = imaging.Rotate180(p.Image)
+		case 270:
+			p.Image = imaging.Rotate90(p.Image)
+		}
+	}
+	if p.Back != nil {
+		switch degrees {
+		case 90:
+			p.Back = imaging.Rotate90(p.Back)
+		case 180:
+			p.Back = imaging.Rotate180(p.Back)
+		case 270:
+			p.Back = imaging.Rotate270(p.Back)
+		}
+	}
+	p.Padding = p.Padding.rotate(degrees)
+	if p.Outline == nil {
+		for i := range p.Joints {
+			if p.Joints[i].Side != WHIMSY_SIDE {
+				p.Joints[i].Side = RotateSide(p.Joints[i].Side, degrees)
+			}
+		}
+	}
+	p.Rotation = (p.Rotation + degrees) % 360
+	return nil
+}
+
+// rotatePieces gives every piece a random rotation picked from the seed
+func rotatePieces(pieces []*Piece, seed int64) error {
+	r := rand.New(rand.NewSource(seed))
+	for _, p := range pieces {
+		if err := p.Rotate(r.Intn(4) * 90); err != nil {
+			return err
+		}
+	}
+	return nil
+}
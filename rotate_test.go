@@ -0,0 +1,78 @@
+package jigsaw_test
+
+import (
+	"image"
+	"image/color"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/disintegration/imaging"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func TestPieceRotate(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 40, 20))
+	img.Set(0, 0, color.White)
+	piece := &jigsaw.Piece{
+		Image: img,
+		Joints: []jigsaw.PieceJoint{
+			{Side: jigsaw.RIGHT_SIDE, External: true},
+			{Side: jigsaw.TOP_SIDE, External: false},
+		},
+	}
+	assert.NoError(t, piece.Rotate(90))
+	assert.Equal(t, 90, piece.Rotation)
+	assert.Equal(t, image.Pt(20, 40), piece.Image.Bounds().Size())
+	//the top left corner ends up top right when turned clockwise
+	assert.Equal(t, color.NRGBA{255, 255, 255, 255}, piece.Image.At(19, 0))
+	assert.Equal(t, jigsaw.BOTTOM_SIDE, piece.Joints[0].Side)
+	assert.Equal(t, jigsaw.RIGHT_SIDE, piece.Joints[1].Side)
+
+	assert.NoError(t, piece.Rotate(270))
+	assert.Equal(t, 0, piece.Rotation)
+	assert.Equal(t, jigsaw.RIGHT_SIDE, piece.Joints[0].Side)
+	assert.Equal(t, jigsaw.TOP_SIDE, piece.Joints[1].Side)
+
+	assert.NoError(t, piece.Rotate(-90))
+	assert.Equal(t, 270, piece.Rotation)
+	assert.Equal(t, jigsaw.TOP_SIDE, piece.Joints[0].Side)
+
+	assert.Error(t, piece.Rotate(45), "expected an error for a rotation that is not a right angle")
+}
+
+func TestBuildWithRotation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rotate")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	img := openImage(t, JPG_SAMPLE)
+	solved, err := jigsaw.NewJigsawBuilderWithPieceCutter(img, 16, jigsaw.JigsawPieceCutter{}).Build()
+	assert.NoError(t, err, "did not expect an error")
+
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(img, 16, jigsaw.JigsawPieceCutter{OutputDir: dir})
+	builder.Rotate = true
+	builder.Seed = 99
+	jig, err := builder.Build()
+	assert.NoError(t, err, "did not expect an error")
+	assert.Equal(t, int64(99), jig.Seed)
+
+	rotated := 0
+	for i, p := range jig.Pieces {
+		assert.Equal(t, 0, p.Rotation%90, "rotation should be a right angle")
+		if p.Rotation != 0 {
+			rotated++
+		}
+		for j, joint := range p.Joints {
+			assert.Equal(t, jigsaw.RotateSide(solved.Pieces[i].Joints[j].Side, p.Rotation), joint.Side)
+		}
+		//the saved image is turned too
+		assert.Equal(t, filepath.Join(dir, p.Name+".png"), p.Path)
+		saved, err := imaging.Open(p.Path)
+		if assert.NoError(t, err, "expected piece %d to be saved", p.Index) {
+			assert.Equal(t, imaging.Clone(p.Image), imaging.Clone(saved), "expected piece %d to be saved turned %d", p.Index, p.Rotation)
+		}
+	}
+	assert.True(t, rotated > 0, "expected some pieces to be rotated")
+}
@@ -0,0 +1,213 @@
+package jigsaw
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/disintegration/imaging"
+)
+
+// DEFAULT_SALIENCY_SHIFT and MAX_SALIENCY_SHIFT are how far a grid line can be moved, as a fraction of the
+// size of a piece. Past the max the pieces on either side could get too thin for their tabs
+const (
+	DEFAULT_SALIENCY_SHIFT = 0.2
+	MAX_SALIENCY_SHIFT     = 0.3
+)
+
+// how much more a protected pixel counts than the most detailed pixel could
+const protectWeight = 100.0
+
+// how many places along its edge each tab is tried at
+const tabPlaces = 11
+
+// energies are kept in thousandths so that sums of them are exact
+const energyScale = 1000
+
+// Saliency keeps the cuts of a grid away from the parts of the image that look bad cut through, such as
+// faces and text. With Detail the busy parts of the image, where it changes most from pixel to pixel, are
+// avoided and Protect marks regions to keep clear of, its pixels that are more than half opaque, stretched
+// to the board if it is another size. Each grid line moves, staying straight, by up to Shift of a piece,
+// 0 uses DEFAULT_SALIENCY_SHIFT, and each tab moves along its edge by up to MAX_TAB_JITTER
+type Saliency struct {
+	Detail  bool
+	Protect image.Image
+	Shift   float64
+}
+
+var ErrSaliencyGrid = errors.New("saliency can only move the lines of a grid")
+
+func (s *Saliency) validate(jb *JigsawBuilder) error {
+	if s == nil {
+		return nil
+	}
+	if jb.Tiling != nil {
+		return ErrSaliencyGrid
+	}
+	if s.Shift < 0 || s.Shift > MAX_SALIENCY_SHIFT {
+		return fmt.Errorf("saliency shift should be between 0 and %v", MAX_SALIENCY_SHIFT)
+	}
+	if !s.Detail && s.Protect == nil {
+		return errors.New("saliency needs detail or a protected mask")
+	}
+	return nil
+}
+
+// energy is how much each pixel of the board should be kept clear of cuts, kept as a summed area table
+// so the energy of any rectangle can be read straight off
+type energy struct {
+	board image.Rectangle
+	sum   []int64
+}
+
+// newEnergy measures the energy of the image
+func (s *Saliency) newEnergy(img image.Image) *energy {
+	board := img.Bounds()
+	w, h := board.Dx(), board.Dy()
+	pixels := make([]float64, w*h)
+	if s.Detail {
+		grey := make([]float64, w*h)
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				r, g, b, _ := img.At(board.Min.X+x, board.Min.Y+y).RGBA()
+				grey[y*w+x] = (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0xffff
+			}
+		}
+		at := func(x, y int) float64 {
+			return grey[minInt(maxInt(y, 0), h-1)*w+minInt(maxInt(x, 0), w-1)]
+		}
+		//sobel
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				gx := at(x+1, y-1) + 2*at(x+1, y) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x-1, y) - at(x-1, y+1)
+				gy := at(x-1, y+1) + 2*at(x, y+1) + at(x+1, y+1) - at(x-1, y-1) - 2*at(x, y-1) - at(x+1, y-1)
+				pixels[y*w+x] = math.Sqrt(gx*gx + gy*gy)
+			}
+		}
+	}
+	if s.Protect != nil {
+		mask := s.Protect
+		if mask.Bounds().Size() != board.Size() {
+			mask = imaging.Resize(mask, w, h, imaging.NearestNeighbor)
+		}
+		min := mask.Bounds().Min
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				if _, _, _, a := mask.At(min.X+x, min.Y+y).RGBA(); a >= 0x8000 {
+					pixels[y*w+x] += protectWeight
+				}
+			}
+		}
+	}
+	e := &energy{board: board, sum: make([]int64, (w+1)*(h+1))}
+	for y := 0; y < h; y++ {
+		row := int64(0)
+		for x := 0; x < w; x++ {
+			row += int64(pixels[y*w+x]*energyScale + 0.5)
+			e.sum[(y+1)*(w+1)+x+1] = e.sum[y*(w+1)+x+1] + row
+		}
+	}
+	return e
+}
+
+// in is the energy of the part of the rectangle that is on the board
+func (e *energy) in(r image.Rectangle) int64 {
+	r = r.Intersect(e.board).Sub(e.board.Min)
+	if r.Empty() {
+		return 0
+	}
+	w := e.board.Dx() + 1
+	return e.sum[r.Max.Y*w+r.Max.X] - e.sum[r.Min.Y*w+r.Max.X] - e.sum[r.Max.Y*w+r.Min.X] + e.sum[r.Min.Y*w+r.Min.X]
+}
+
+// moveLine finds where between at-shift and at+shift a line crossing the board is in the least energy,
+// line giving the pixels either side of it at each place. The nearest place to at wins a tie
+func moveLine(at, shift int, line func(int) image.Rectangle, e *energy) int {
+	best, bestEnergy := at, e.in(line(at))
+	for d := 1; d <= shift; d++ {
+		for _, to := range []int{at - d, at + d} {
+			if en := e.in(line(to)); en < bestEnergy {
+				best, bestEnergy = to, en
+			}
+		}
+	}
+	return best
+}
+
+// moveGrid moves the lines between the rows and columns of pieces out of the energy. Each piece keeps its
+// Width and Height, which size its tabs, and is cut from its moved cell
+func (s *Saliency) moveGrid(pieces []*Piece, rows, cols int, e *energy) {
+	if len(pieces) == 0 {
+		return
+	}
+	first := pieces[0]
+	shift := s.Shift
+	if shift == 0 {
+		shift = DEFAULT_SALIENCY_SHIFT
+	}
+	//a line at x runs between the pixels x-1 and x
+	xs, ys := make([]int, cols+1), make([]int, rows+1)
+	for c := range xs {
+		xs[c] = first.Points[0].X + c*first.Width
+		if c > 0 && c < cols {
+			xs[c] = moveLine(xs[c], int(shift*float64(first.Width)), func(x int) image.Rectangle {
+				return image.Rect(x-1, e.board.Min.Y, x+1, e.board.Max.Y)
+			}, e)
+		}
+	}
+	for r := range ys {
+		ys[r] = first.Points[0].Y + r*first.Height
+		if r > 0 && r < rows {
+			ys[r] = moveLine(ys[r], int(shift*float64(first.Height)), func(y int) image.Rectangle {
+				return image.Rect(e.board.Min.X, y-1, e.board.Max.X, y+1)
+			}, e)
+		}
+	}
+	for _, p := range pieces {
+		cell := image.Rect(xs[p.Col], ys[p.Row], xs[p.Col+1], ys[p.Row+1])
+		p.cell = cell
+		p.Points = []image.Point{cell.Min, image.Pt(cell.Max.X, cell.Min.Y), image.Pt(cell.Min.X, cell.Max.Y), cell.Max}
+		p.Bounds = cell
+	}
+}
+
+// placeTabs moves each tab along its edge to where the tab and the blank it fits are in the least energy,
+// the nearest place to where the tab already was winning a tie
+func (s *Saliency) placeTabs(pieces []*Piece, tabSize float64, e *energy) {
+	eachEdge(pieces, func(p *Piece, tab, blank *PieceJoint) {
+		if blank == nil {
+			return
+		}
+		size := tab.Size
+		if size == 0 {
+			size = tabSize
+		}
+		radius := Percentage(p, size)
+		if radius == 0 {
+			return
+		}
+		cell := p.gridCell()
+		length := edgeLength(*tab, cell)
+		limit := length/2 - radius
+		if limit <= 0 {
+			return
+		}
+		best, bestEnergy := tab.Offset, int64(-1)
+		for i := 0; i < tabPlaces; i++ {
+			offset := MAX_TAB_JITTER * (2*float64(i)/float64(tabPlaces-1) - 1)
+			shift := minInt(maxInt(int(offset*float64(length)), -limit), limit)
+			var centre image.Point
+			if tab.Side == RIGHT_SIDE {
+				centre = image.Pt(cell.Max.X, (cell.Min.Y+cell.Max.Y)/2+shift)
+			} else {
+				centre = image.Pt((cell.Min.X+cell.Max.X)/2+shift, cell.Max.Y)
+			}
+			en := e.in(image.Rectangle{centre, centre}.Inset(-radius))
+			if bestEnergy < 0 || en < bestEnergy || (en == bestEnergy && math.Abs(offset-tab.Offset) < math.Abs(best-tab.Offset)) {
+				best, bestEnergy = offset, en
+			}
+		}
+		tab.Offset, blank.Offset = best, best
+	})
+}
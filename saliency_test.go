@@ -0,0 +1,81 @@
+package jigsaw_test
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func salientJigsaw(t *testing.T, img image.Image, saliency *jigsaw.Saliency, cutter jigsaw.JigsawPieceCutter) jigsaw.Jigsaw {
+	return buildJigsaw(t, img, 16, cutter, func(b *jigsaw.JigsawBuilder) {
+		b.Saliency = saliency
+	})
+}
+
+// band is a mask the size of the board with the rectangle protected
+func band(r image.Rectangle) image.Image {
+	mask := image.NewAlpha(image.Rect(0, 0, 240, 240))
+	draw.Draw(mask, r, image.Opaque, image.ZP, draw.Src)
+	return mask
+}
+
+func TestSaliencyMovesLinesOutOfProtectedRegions(t *testing.T) {
+	flat := jigsaw.JigsawPieceCutter{JointStyle: jigsaw.JOINT_FLAT}
+	jig := salientJigsaw(t, filled(240, 240, color.White), &jigsaw.Saliency{Protect: band(image.Rect(52, 0, 68, 240))}, flat)
+	line := jig.Pieces[0].Bounds.Max.X
+	assert.True(t, line <= 52 || line >= 68, "expected the line at 60 to move out of the band, it is at %d", line)
+	assert.Equal(t, line, jig.Pieces[1].Bounds.Min.X, "expected the next piece to start where the first ends")
+	assert.Equal(t, 120, jig.Pieces[1].Bounds.Max.X, "expected the other lines to stay put")
+	assert.Equal(t, 60, jig.Pieces[0].Width, "expected the pieces to keep their size")
+	assert.Equal(t, []int{2, 5}, jig.Pieces[0].Neighbours())
+
+	//a mask of another size is stretched to the board
+	jig = salientJigsaw(t, filled(240, 240, color.White), &jigsaw.Saliency{Protect: filled(24, 24, color.Transparent)}, flat)
+	assert.Equal(t, 60, jig.Pieces[0].Bounds.Max.X)
+}
+
+func TestSaliencyAvoidsDetail(t *testing.T) {
+	img := filled(240, 240, color.White)
+	//stripes across the line between the second and third rows
+	for y := 112; y < 128; y += 4 {
+		draw.Draw(img, image.Rect(0, y, 240, y+2), image.Black, image.ZP, draw.Src)
+	}
+	jig := salientJigsaw(t, img, &jigsaw.Saliency{Detail: true, Shift: jigsaw.MAX_SALIENCY_SHIFT}, jigsaw.JigsawPieceCutter{})
+	line := jig.Pieces[4].Bounds.Max.Y - jigsaw.Percentage(jig.Pieces[4], jigsaw.PERCENTAGE)
+	assert.True(t, line < 112 || line > 126, "expected the line at 120 to move off the stripes, it is at %d", line)
+	covered := coverage(jig)
+	assert.Len(t, covered, 240*240, "expected the pieces to cover the board")
+	for p, n := range covered {
+		if n != 1 {
+			t.Fatalf("expected %v to be in exactly one piece, it is in %d", p, n)
+		}
+	}
+}
+
+func TestSaliencyMovesTabs(t *testing.T) {
+	//protect where the tab between the first two pieces would go, away from any line
+	jig := salientJigsaw(t, filled(240, 240, color.White), &jigsaw.Saliency{Protect: band(image.Rect(48, 24, 72, 36))}, jigsaw.JigsawPieceCutter{})
+	right := jointOn(jig.Pieces[0], jigsaw.RIGHT_SIDE)
+	left := jointOn(jig.Pieces[1], jigsaw.LEFT_SIDE)
+	assert.NotEqual(t, 0.0, right.Offset, "expected the tab to move out of the protected region")
+	assert.Equal(t, right.Offset, left.Offset, "expected the blank to move with it")
+	assert.Equal(t, 0.0, jointOn(jig.Pieces[5], jigsaw.RIGHT_SIDE).Offset, "expected tabs with nothing to avoid to stay put")
+}
+
+func TestSaliencyErrors(t *testing.T) {
+	for _, s := range []*jigsaw.Saliency{{}, {Detail: true, Shift: 0.5}} {
+		builder := jigsaw.NewJigsawBuilderWithPieceCutter(filled(100, 100, color.White), 4, jigsaw.JigsawPieceCutter{})
+		builder.Saliency = s
+		_, err := builder.Build()
+		assert.Error(t, err)
+	}
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(filled(100, 100, color.White), 4, jigsaw.JigsawPieceCutter{})
+	builder.Saliency = &jigsaw.Saliency{Detail: true}
+	builder.Tiling = jigsaw.HexTiling{}
+	_, err := builder.Build()
+	assert.Equal(t, jigsaw.ErrSaliencyGrid, err)
+}
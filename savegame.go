@@ -0,0 +1,283 @@
+package jigsaw
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"time"
+)
+
+// SAVE_VERSION is the version of the format written by Game.Save. LoadGame reads every version up to it.
+//
+//	1 pieces and elapsed time
+//	2 adds the undo and redo history
+//	3 adds the stats of each player and who made each move in the history
+const SAVE_VERSION = 3
+
+const saveMagic = "JGSV"
+
+const (
+	maxSavedCommands = 1 << 16
+	maxSavedString   = 1 << 10
+)
+
+var (
+	ErrNotASave      = errors.New("not a saved game")
+	ErrSaveMismatch  = errors.New("saved game is for a different jigsaw")
+	ErrCorruptedSave = errors.New("saved game is corrupted")
+)
+
+// Save writes the game in a compact binary format: a header identifying the jigsaw by its seed and grid,
+// how long it has been played for, the position, rotation and group of every piece, the undo and redo
+// history and then the stats of every player, all as varints
+func (g *Game) Save(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	sw := &saveWriter{w: bw}
+	bw.WriteString(saveMagic)
+	sw.int(SAVE_VERSION)
+	sw.int(g.Jigsaw.Seed)
+	sw.int(int64(g.Jigsaw.Rows))
+	sw.int(int64(g.Jigsaw.Cols))
+	sw.int(int64(len(g.Jigsaw.Pieces)))
+	sw.int(int64(g.Elapsed()))
+	for _, s := range g.Pieces() {
+		sw.int(int64(s.Index))
+		sw.int(int64(s.Position.X))
+		sw.int(int64(s.Position.Y))
+		sw.int(int64(s.Rotation / 90))
+		sw.int(int64(g.groups.find(s.Index)))
+	}
+	sw.commands(g.undo)
+	sw.commands(g.redo)
+	sw.stats(g.AllStats())
+	if sw.err != nil {
+		return sw.err
+	}
+	return bw.Flush()
+}
+
+// LoadGame resumes a saved game of the jigsaw. Saves of any other jigsaw are rejected with ErrSaveMismatch
+func LoadGame(jig Jigsaw, r io.Reader) (*Game, error) {
+	br := bufio.NewReader(r)
+	magic := make([]byte, len(saveMagic))
+	if _, err := io.ReadFull(br, magic); err != nil || string(magic) != saveMagic {
+		return nil, ErrNotASave
+	}
+	sr := &saveReader{r: br}
+	version := sr.int()
+	if sr.err == nil && (version < 1 || version > SAVE_VERSION) {
+		return nil, fmt.Errorf("saved game version %d is not supported", version)
+	}
+	seed, rows, cols, count := sr.int(), sr.int(), sr.int(), sr.int()
+	if sr.err != nil {
+		return nil, ErrCorruptedSave
+	}
+	if seed != jig.Seed || rows != int64(jig.Rows) || cols != int64(jig.Cols) || count != int64(len(jig.Pieces)) {
+		return nil, ErrSaveMismatch
+	}
+	elapsed := time.Duration(sr.int())
+
+	layout := &Layout{Seed: seed, Placements: make([]Placement, 0, count)}
+	roots := make(map[int]int, count)
+	pieces := make(map[int]*Piece, len(jig.Pieces))
+	for _, p := range jig.Pieces {
+		pieces[p.Index] = p
+	}
+	for i := int64(0); i < count; i++ {
+		index, x, y, turns, root := int(sr.int()), int(sr.int()), int(sr.int()), int(sr.int()), int(sr.int())
+		//a save cut short reads as zeroes, which are not a piece of another jigsaw
+		if sr.err != nil {
+			return nil, ErrCorruptedSave
+		}
+		p, ok := pieces[index]
+		if !ok {
+			return nil, ErrSaveMismatch
+		}
+		rotation := ((turns*90-p.Rotation)%360 + 360) % 360
+		layout.Placements = append(layout.Placements, Placement{Index: index, Position: image.Pt(x, y), Rotation: rotation})
+		roots[index] = root
+	}
+	if sr.err != nil {
+		return nil, ErrCorruptedSave
+	}
+	g, err := NewGame(jig, layout)
+	if err != nil {
+		return nil, ErrSaveMismatch
+	}
+	for index, root := range roots {
+		if _, ok := pieces[root]; !ok {
+			return nil, ErrCorruptedSave
+		}
+		g.groups.union(root, index)
+	}
+	g.elapsed = elapsed
+	if version >= 2 {
+		g.undo, g.redo = sr.commands(pieces, version), sr.commands(pieces, version)
+	}
+	if version >= 3 {
+		for _, stats := range sr.stats() {
+			g.playerStats(stats.Player).Stats = stats
+		}
+	}
+	if sr.err != nil {
+		return nil, ErrCorruptedSave
+	}
+	return g, nil
+}
+
+type saveWriter struct {
+	w   io.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (sw *saveWriter) int(v int64) {
+	if sw.err != nil {
+		return
+	}
+	n := binary.PutVarint(sw.buf[:], v)
+	_, sw.err = sw.w.Write(sw.buf[:n])
+}
+
+func (sw *saveWriter) commands(commands []command) {
+	sw.int(int64(len(commands)))
+	for _, c := range commands {
+		sw.states(c.before)
+		sw.states(c.after)
+		sw.int(int64(len(c.merges)))
+		for _, m := range c.merges {
+			sw.int(int64(m.with))
+			sw.int(int64(len(m.pieces)))
+			for _, p := range m.pieces {
+				sw.int(int64(p))
+			}
+		}
+		sw.string(c.player)
+	}
+}
+
+func (sw *saveWriter) stats(all []Stats) {
+	sw.int(int64(len(all)))
+	for _, s := range all {
+		sw.string(s.Player)
+		sw.int(int64(s.Elapsed))
+		sw.int(int64(s.Moves))
+		sw.int(int64(s.Snaps))
+		sw.int(int64(s.Hints))
+	}
+}
+
+func (sw *saveWriter) string(s string) {
+	sw.int(int64(len(s)))
+	if sw.err == nil {
+		_, sw.err = io.WriteString(sw.w, s)
+	}
+}
+
+func (sw *saveWriter) states(states []PieceState) {
+	sw.int(int64(len(states)))
+	for _, s := range states {
+		sw.int(int64(s.Index))
+		sw.int(int64(s.Position.X))
+		sw.int(int64(s.Position.Y))
+		sw.int(int64(s.Rotation / 90))
+	}
+}
+
+type saveReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (sr *saveReader) int() int64 {
+	if sr.err != nil {
+		return 0
+	}
+	var v int64
+	v, sr.err = binary.ReadVarint(sr.r)
+	return v
+}
+
+// count reads the length of a list, anything longer than max can only come from a corrupted save
+func (sr *saveReader) count(max int) int {
+	n := sr.int()
+	if sr.err == nil && (n < 0 || n > int64(max)) {
+		sr.err = ErrCorruptedSave
+	}
+	if sr.err != nil {
+		return 0
+	}
+	return int(n)
+}
+
+// index reads the Index of a piece, failing if the jigsaw has no such piece
+func (sr *saveReader) index(pieces map[int]*Piece) int {
+	i := int(sr.int())
+	if _, ok := pieces[i]; sr.err == nil && !ok {
+		sr.err = ErrCorruptedSave
+	}
+	return i
+}
+
+func (sr *saveReader) commands(pieces map[int]*Piece, version int64) []command {
+	commands := make([]command, sr.count(maxSavedCommands))
+	for i := range commands {
+		commands[i].before = sr.states(pieces)
+		commands[i].after = sr.states(pieces)
+		commands[i].merges = make([]merge, sr.count(len(pieces)))
+		for j := range commands[i].merges {
+			m := &commands[i].merges[j]
+			m.with = sr.index(pieces)
+			m.pieces = make([]int, sr.count(len(pieces)))
+			for k := range m.pieces {
+				m.pieces[k] = sr.index(pieces)
+			}
+			if sr.err == nil && len(m.pieces) == 0 {
+				sr.err = ErrCorruptedSave
+			}
+		}
+		if version >= 3 {
+			commands[i].player = sr.string()
+		}
+		if sr.err != nil {
+			return nil
+		}
+	}
+	return commands
+}
+
+func (sr *saveReader) states(pieces map[int]*Piece) []PieceState {
+	states := make([]PieceState, sr.count(len(pieces)))
+	for i := range states {
+		states[i].Index = sr.index(pieces)
+		states[i].Position = image.Pt(int(sr.int()), int(sr.int()))
+		states[i].Rotation = int(((sr.int()%4)+4)%4) * 90
+	}
+	return states
+}
+
+func (sr *saveReader) string() string {
+	b := make([]byte, sr.count(maxSavedString))
+	if sr.err == nil {
+		_, sr.err = io.ReadFull(sr.r, b)
+	}
+	return string(b)
+}
+
+func (sr *saveReader) stats() []Stats {
+	all := make([]Stats, sr.count(maxSavedCommands))
+	for i := range all {
+		all[i].Player = sr.string()
+		all[i].Elapsed = time.Duration(sr.int())
+		all[i].Moves = int(sr.int())
+		all[i].Snaps = int(sr.int())
+		all[i].Hints = int(sr.int())
+	}
+	if sr.err != nil {
+		return nil
+	}
+	return all
+}
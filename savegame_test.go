@@ -0,0 +1,81 @@
+package jigsaw_test
+
+import (
+	"bytes"
+	"image"
+	"testing"
+	"time"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func playedGame(t *testing.T) *jigsaw.Game {
+	g := newGame(t)
+	assert.NoError(t, g.Move(1, image.Pt(-40, 25)))
+	assert.NoError(t, g.Move(2, image.Pt(60, 25)))
+	snapped, _ := g.Drop(2)
+	assert.True(t, snapped, "expected piece to snap")
+	assert.NoError(t, g.Rotate(3, 270))
+	return g
+}
+
+func TestSaveAndLoadGame(t *testing.T) {
+	g := playedGame(t)
+	time.Sleep(10 * time.Millisecond)
+	buf := &bytes.Buffer{}
+	assert.NoError(t, g.Save(buf))
+	assert.True(t, buf.Len() < 128, "expected a compact save but it was %d bytes", buf.Len())
+
+	loaded, err := jigsaw.LoadGame(g.Jigsaw, buf)
+	assert.NoError(t, err, "did not expect an error loading game")
+	assert.Equal(t, g.Pieces(), loaded.Pieces())
+	assert.Equal(t, g.Groups(), loaded.Groups())
+	assert.True(t, loaded.Elapsed() >= 10*time.Millisecond, "expected elapsed time to be restored")
+
+	//the loaded game carries on as before
+	assert.NoError(t, loaded.Move(2, image.Pt(0, 0)))
+	one, _ := loaded.Piece(1)
+	assert.Equal(t, image.Pt(-100, 0), one.Position)
+
+	//including its history
+	assert.NoError(t, loaded.Undo())
+	assert.NoError(t, loaded.Undo())
+	assert.NoError(t, loaded.Undo())
+	assert.Len(t, loaded.Groups(), 4, "expected the snap before saving to be undone")
+	assert.NoError(t, loaded.Redo())
+	assert.True(t, loaded.CanRedo())
+}
+
+func TestLoadGameRejectsOtherJigsaws(t *testing.T) {
+	g := playedGame(t)
+	buf := &bytes.Buffer{}
+	assert.NoError(t, g.Save(buf))
+	save := buf.Bytes()
+
+	other := g.Jigsaw
+	other.Seed = 12
+	_, err := jigsaw.LoadGame(other, bytes.NewReader(save))
+	assert.Equal(t, jigsaw.ErrSaveMismatch, err)
+
+	other = g.Jigsaw
+	other.Rows = 4
+	_, err = jigsaw.LoadGame(other, bytes.NewReader(save))
+	assert.Equal(t, jigsaw.ErrSaveMismatch, err)
+
+	_, err = jigsaw.LoadGame(g.Jigsaw, bytes.NewReader([]byte("not a save at all")))
+	assert.Equal(t, jigsaw.ErrNotASave, err)
+
+	_, err = jigsaw.LoadGame(g.Jigsaw, bytes.NewReader(save[:len(save)-3]))
+	assert.Equal(t, jigsaw.ErrCorruptedSave, err)
+
+	//wherever the save is cut short
+	for n := len("JGSV"); n < len(save); n++ {
+		_, err = jigsaw.LoadGame(g.Jigsaw, bytes.NewReader(save[:n]))
+		assert.Equal(t, jigsaw.ErrCorruptedSave, err, "expected a save cut at %d of %d bytes to be corrupted", n, len(save))
+	}
+
+	newer := append([]byte("JGSV"), 0x7e)
+	_, err = jigsaw.LoadGame(g.Jigsaw, bytes.NewReader(newer))
+	assert.Error(t, err, "expected a newer version to be rejected")
+}
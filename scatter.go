@@ -0,0 +1,103 @@
+package jigsaw
+
+import (
+	"errors"
+	"image"
+	"math/rand"
+)
+
+// Layout is the starting arrangement of the pieces of a Jigsaw in a play area.
+// It is kept separate from the Jigsaw so that a single cut can be played with many layouts.
+type Layout struct {
+	Seed       int64           `json:"seed"`
+	Area       image.Rectangle `json:"area"`
+	Board      image.Rectangle `json:"board"`
+	Placements []Placement     `json:"placements"`
+}
+
+// Placement is where a piece starts in the play area. Position is the top left of the piece image and
+// Rotation is in degrees clockwise on top of any Rotation the piece was cut with
+type Placement struct {
+	Index    int         `json:"index"`
+	Position image.Point `json:"position"`
+	Rotation int         `json:"rotation"`
+}
+
+// Placement returns the placement of the piece with the given Index
+func (l *Layout) Placement(index int) (Placement, bool) {
+	for _, p := range l.Placements {
+		if p.Index == index {
+			return p, true
+		}
+	}
+	return Placement{}, false
+}
+
+// Scatter lays the pieces of the jigsaw out around the board, which is centered in the play area.
+// The play area is split into cells big enough to hold any piece in any rotation, the cells that do
+// not touch the board are shuffled and each piece is dropped somewhere inside its own cell so no two
+// pieces overlap. The same jigsaw, area and seed always give the same layout.
+func Scatter(jig Jigsaw, area image.Rectangle, seed int64, rotate bool) (*Layout, error) {
+	if len(jig.Pieces) == 0 {
+		return nil, errors.New("jigsaw has no pieces to scatter")
+	}
+	boardSize := jig.Bounds.Size()
+	if boardSize.X > area.Dx() || boardSize.Y > area.Dy() {
+		return nil, errors.New("play area is smaller than the board")
+	}
+	offset := image.Pt((area.Dx()-boardSize.X)/2, (area.Dy()-boardSize.Y)/2)
+	board := image.Rectangle{Min: area.Min.Add(offset), Max: area.Min.Add(offset).Add(boardSize)}
+
+	cellSize := 0
+	for _, p := range jig.Pieces {
+		if p.Bounds.Dx() > cellSize {
+			cellSize = p.Bounds.Dx()
+		}
+		if p.Bounds.Dy() > cellSize {
+			cellSize = p.Bounds.Dy()
+		}
+	}
+	if cellSize == 0 {
+		return nil, errors.New("jigsaw pieces have no size")
+	}
+
+	cells := make([]image.Rectangle, 0)
+	for y := area.Min.Y; y+cellSize <= area.Max.Y; y += cellSize {
+		for x := area.Min.X; x+cellSize <= area.Max.X; x += cellSize {
+			cell := image.Rect(x, y, x+cellSize, y+cellSize)
+			if !cell.Overlaps(board) {
+				cells = append(cells, cell)
+			}
+		}
+	}
+	if len(cells) < len(jig.Pieces) {
+		return nil, errors.New("play area does not have enough room around the board for every piece")
+	}
+
+	r := rand.New(rand.NewSource(seed))
+	for i := len(cells) - 1; i > 0; i-- {
+		j := r.Intn(i + 1)
+		cells[i], cells[j] = cells[j], cells[i]
+	}
+
+	layout := &Layout{Seed: seed, Area: area, Board: board, Placements: make([]Placement, len(jig.Pieces))}
+	for i, p := range jig.Pieces {
+		size := p.Bounds.Size()
+		rotation := 0
+		if rotate {
+			rotation = r.Intn(4) * 90
+		}
+		if turned := (p.Rotation + rotation) % 360; turned == 90 || turned == 270 {
+			size = image.Pt(size.Y, size.X)
+		}
+		pos := cells[i].Min
+		if slack := cellSize - size.X; slack > 0 {
+			pos.X += r.Intn(slack + 1)
+		}
+		if slack := cellSize - size.Y; slack > 0 {
+			pos.Y += r.Intn(slack + 1)
+		}
+		layout.Placements[i] = Placement{Index: p.Index, Position: pos, Rotation: rotation}
+	}
+	return layout, nil
+}
@@ -0,0 +1,72 @@
+package jigsaw_test
+
+import (
+	"encoding/json"
+	"image"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func scatterJigsaw(t *testing.T) jigsaw.Jigsaw {
+	bounds := image.Rect(0, 0, 400, 300)
+	builder := jigsaw.NewJigsawBuilder(image.NewRGBA(bounds), 12)
+	builder.NumRows = 3
+	pieces, err := builder.BuildPieces()
+	assert.NoError(t, err, "did not expect an error building pieces")
+	return jigsaw.Jigsaw{Rows: 3, Pieces: pieces, Bounds: bounds}
+}
+
+func TestScatterPlacesEveryPieceWithoutOverlap(t *testing.T) {
+	jig := scatterJigsaw(t)
+	area := image.Rect(0, 0, 1200, 900)
+	layout, err := jigsaw.Scatter(jig, area, 42, true)
+	assert.NoError(t, err, "did not expect an error")
+	assert.Len(t, layout.Placements, len(jig.Pieces))
+	assert.Equal(t, image.Rect(400, 300, 800, 600), layout.Board)
+
+	placed := make([]image.Rectangle, 0)
+	for _, p := range layout.Placements {
+		size := jig.Pieces[p.Index-1].Bounds.Size()
+		if p.Rotation == 90 || p.Rotation == 270 {
+			size = image.Pt(size.Y, size.X)
+		}
+		r := image.Rectangle{Min: p.Position, Max: p.Position.Add(size)}
+		assert.True(t, r.In(area), "piece %d should be inside the play area", p.Index)
+		assert.False(t, r.Overlaps(layout.Board), "piece %d should not be on the board", p.Index)
+		for _, other := range placed {
+			assert.False(t, r.Overlaps(other), "piece %d overlaps another piece", p.Index)
+		}
+		placed = append(placed, r)
+	}
+}
+
+func TestScatterIsRepeatableForSeed(t *testing.T) {
+	jig := scatterJigsaw(t)
+	area := image.Rect(0, 0, 1200, 900)
+	first, err := jigsaw.Scatter(jig, area, 7, true)
+	assert.NoError(t, err, "did not expect an error")
+	second, err := jigsaw.Scatter(jig, area, 7, true)
+	assert.NoError(t, err, "did not expect an error")
+	assert.Equal(t, first, second)
+
+	encoded, err := json.Marshal(first)
+	assert.NoError(t, err, "did not expect an error encoding layout")
+	decoded := &jigsaw.Layout{}
+	assert.NoError(t, json.Unmarshal(encoded, decoded))
+	assert.Equal(t, first, decoded)
+}
+
+func TestScatterWithoutRotation(t *testing.T) {
+	layout, err := jigsaw.Scatter(scatterJigsaw(t), image.Rect(0, 0, 1200, 900), 3, false)
+	assert.NoError(t, err, "did not expect an error")
+	for _, p := range layout.Placements {
+		assert.Equal(t, 0, p.Rotation)
+	}
+}
+
+func TestScatterAreaTooSmall(t *testing.T) {
+	_, err := jigsaw.Scatter(scatterJigsaw(t), image.Rect(0, 0, 450, 350), 1, false)
+	assert.Error(t, err, "expected an error when there is no room for the pieces")
+}
@@ -0,0 +1,530 @@
+// Package server is an http service that cuts uploaded images into jigsaws and serves the results.
+//
+//	POST /puzzles                       multipart form with an "image" file, an optional "back" file the same
+//	                                    size for double sided pieces and optional fields, see Options. Cuts
+//	                                    the image in the background and returns the job
+//	GET  /puzzles/{id}                  the status of the job
+//	GET  /puzzles/{id}/manifest         the manifest of the jigsaw once the job is done
+//	GET  /puzzles/{id}/pieces/{index}   the png image of a piece once the job is done
+//	GET  /puzzles/{id}/pieces/{index}/back  the png image of the back of a double sided piece
+//	GET  /puzzles/{id}/play             websocket joining the shared game of the puzzle, see Session
+package server
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/maleck13/jigsaw"
+)
+
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusFailed  = "failed"
+)
+
+const (
+	DEFAULT_PIECES          = 16
+	DEFAULT_MAX_UPLOAD_SIZE = 32 << 20
+)
+
+// Job is a request to cut an image into a jigsaw
+type Job struct {
+	ID     string  `json:"id"`
+	Status string  `json:"status"`
+	Error  string  `json:"error,omitempty"`
+	Opts   Options `json:"options"`
+}
+
+// the layouts a puzzle can be cut in, a grid or one of the tilings
+const (
+	LAYOUT_GRID     = "grid"
+	LAYOUT_VORONOI  = "voronoi"
+	LAYOUT_HEX      = "hex"
+	LAYOUT_TRIANGLE = "triangle"
+)
+
+// Options are the choices made when uploading an image, each given by the form field of the same name as
+// its json. Joints and Tab are the style and size of the cutter's tabs, TabMax varies each tab's size
+// between Tab and it and Jitter moves each tab along its side. Edges and Wave are the edge style and how
+// wavy. Layout is grid or a tiling and Relax evens out voronoi pieces, jigsaw.DEFAULT_RELAX when not given
+type Options struct {
+	Pieces int     `json:"pieces"`
+	Rows   int     `json:"rows,omitempty"`
+	Seed   int64   `json:"seed"`
+	Rotate bool    `json:"rotate"`
+	Mode   string  `json:"mode,omitempty"`
+	Joints string  `json:"joints,omitempty"`
+	Tab    float64 `json:"tab,omitempty"`
+	TabMax float64 `json:"tabMax,omitempty"`
+	Jitter float64 `json:"jitter,omitempty"`
+	Edges  string  `json:"edges,omitempty"`
+	Wave   float64 `json:"wave,omitempty"`
+	Layout string  `json:"layout,omitempty"`
+	Relax  int     `json:"relax,omitempty"`
+}
+
+// Builder sets up cutting the image with the options
+func (o Options) Builder(img image.Image) *jigsaw.JigsawBuilder {
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(img, o.Pieces, jigsaw.JigsawPieceCutter{JointStyle: o.Joints, TabSize: o.Tab})
+	builder.NumRows = o.Rows
+	builder.Seed = o.Seed
+	builder.Rotate = o.Rotate
+	builder.Mode = o.Mode
+	if o.TabMax > 0 {
+		builder.MinTabSize, builder.MaxTabSize = o.Tab, o.TabMax
+		if o.Tab == 0 {
+			builder.MinTabSize = jigsaw.PERCENTAGE
+		}
+	}
+	builder.TabJitter = o.Jitter
+	builder.EdgeStyle = o.Edges
+	builder.WaveAmplitude = o.Wave
+	switch o.Layout {
+	case LAYOUT_VORONOI:
+		builder.Tiling = jigsaw.VoronoiTiling{Relax: o.Relax}
+	case LAYOUT_HEX:
+		builder.Tiling = jigsaw.HexTiling{}
+	case LAYOUT_TRIANGLE:
+		builder.Tiling = jigsaw.TriangleTiling{}
+	}
+	return builder
+}
+
+var ErrNotReady = errors.New("puzzle has not finished being cut")
+
+// Server cuts and serves puzzles, see the package doc. CheckOrigin decides which pages may open the
+// websocket of a game, when nil only those from the server's own host, see SameOrigin
+type Server struct {
+	Storage       Storage
+	MaxUploadSize int64
+	CheckOrigin   func(r *http.Request) bool
+	mu            sync.RWMutex
+	jobs          map[string]*Job
+	sessions      map[string]*Session
+	building      sync.WaitGroup
+}
+
+func New(storage Storage) *Server {
+	return &Server{Storage: storage, MaxUploadSize: DEFAULT_MAX_UPLOAD_SIZE, jobs: make(map[string]*Job), sessions: make(map[string]*Session)}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if parts[0] != "puzzles" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if len(parts) > 1 && !validID(parts[1]) {
+		writeError(w, http.StatusNotFound, "no puzzle with id "+parts[1])
+		return
+	}
+	switch {
+	case len(parts) == 1 && r.Method == "POST":
+		s.create(w, r)
+	case len(parts) == 2 && r.Method == "GET":
+		s.status(w, parts[1])
+	case len(parts) == 3 && parts[2] == "manifest" && r.Method == "GET":
+		s.serveFile(w, parts[1], "manifest.json", "application/json")
+	case len(parts) == 4 && parts[2] == "pieces" && r.Method == "GET":
+		index, err := strconv.Atoi(parts[3])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "piece index should be a number")
+			return
+		}
+		s.serveFile(w, parts[1], pieceFile(index), "image/png")
+	case len(parts) == 5 && parts[2] == "pieces" && parts[4] == "back" && r.Method == "GET":
+		index, err := strconv.Atoi(parts[3])
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "piece index should be a number")
+			return
+		}
+		s.serveFile(w, parts[1], backFile(index), "image/png")
+	case len(parts) == 3 && parts[2] == "play" && r.Method == "GET":
+		s.play(w, r, parts[1])
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// Job returns a copy of the job with the given id
+func (s *Server) Job(id string) (Job, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return *job, true
+}
+
+// Wait blocks until every build that has been started has finished
+func (s *Server) Wait() {
+	s.building.Wait()
+}
+
+func (s *Server) create(w http.ResponseWriter, r *http.Request) {
+	r.Body = http.MaxBytesReader(w, r.Body, s.MaxUploadSize)
+	if err := r.ParseMultipartForm(s.MaxUploadSize); err != nil {
+		writeError(w, http.StatusBadRequest, "could not read upload "+err.Error())
+		return
+	}
+	opts, err := parseOptions(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "an image file is required")
+		return
+	}
+	defer file.Close()
+	img, _, err := jigsaw.DecodeImage(file)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "could not decode image "+err.Error())
+		return
+	}
+	var back image.Image
+	if file, _, err := r.FormFile("back"); err == nil {
+		defer file.Close()
+		if back, _, err = jigsaw.DecodeImage(file); err != nil {
+			writeError(w, http.StatusBadRequest, "could not decode back "+err.Error())
+			return
+		}
+		if back.Bounds().Size() != img.Bounds().Size() {
+			writeError(w, http.StatusBadRequest, jigsaw.ErrBackSize.Error())
+			return
+		}
+	}
+	id, err := newID()
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	job := Job{ID: id, Status: StatusPending, Opts: opts}
+	s.mu.Lock()
+	s.jobs[id] = &job
+	s.mu.Unlock()
+	accepted := job
+
+	s.building.Add(1)
+	go func() {
+		defer s.building.Done()
+		s.build(id, img, back, opts)
+	}()
+	writeJSON(w, http.StatusAccepted, accepted)
+}
+
+func (s *Server) build(id string, img, back image.Image, opts Options) {
+	s.setStatus(id, StatusRunning, nil)
+	builder := opts.Builder(img)
+	builder.Back = back
+	jig, err := builder.Build()
+	if err == nil {
+		err = s.store(id, jig)
+	}
+	if err != nil {
+		s.setStatus(id, StatusFailed, err)
+		return
+	}
+	s.setStatus(id, StatusDone, nil)
+}
+
+// store saves each piece image, and its back, and then the manifest, so that once the manifest can be read
+// every piece in it can be too
+func (s *Server) store(id string, jig jigsaw.Jigsaw) error {
+	manifest := jig.Manifest()
+	for i, p := range jig.Pieces {
+		if err := s.putImage(id, pieceFile(p.Index), p.Image); err != nil {
+			return err
+		}
+		manifest.Pieces[i].Path = fmt.Sprintf("/puzzles/%s/pieces/%d", id, p.Index)
+		manifest.Pieces[i].BackPath = ""
+		if p.Back != nil {
+			if err := s.putImage(id, backFile(p.Index), p.Back); err != nil {
+				return err
+			}
+			manifest.Pieces[i].BackPath = fmt.Sprintf("/puzzles/%s/pieces/%d/back", id, p.Index)
+		}
+	}
+	data, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return s.Storage.Put(id, "manifest.json", data)
+}
+
+func (s *Server) putImage(id, name string, img image.Image) error {
+	buf := &bytes.Buffer{}
+	if err := png.Encode(buf, img); err != nil {
+		return err
+	}
+	return s.Storage.Put(id, name, buf.Bytes())
+}
+
+func (s *Server) setStatus(id, status string, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs[id].Status = status
+	if err != nil {
+		s.jobs[id].Error = err.Error()
+	}
+}
+
+func (s *Server) status(w http.ResponseWriter, id string) {
+	job, ok := s.Job(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no puzzle with id "+id)
+		return
+	}
+	writeJSON(w, http.StatusOK, job)
+}
+
+func (s *Server) serveFile(w http.ResponseWriter, id, name, contentType string) {
+	job, ok := s.Job(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no puzzle with id "+id)
+		return
+	}
+	if job.Status != StatusDone {
+		writeError(w, http.StatusConflict, "puzzle "+id+" is "+job.Status)
+		return
+	}
+	data, err := s.Storage.Get(id, name)
+	if err == ErrNotFound {
+		writeError(w, http.StatusNotFound, name+" not found for puzzle "+id)
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(data)
+}
+
+func (s *Server) play(w http.ResponseWriter, r *http.Request, id string) {
+	session, err := s.Session(id)
+	if err == ErrNotFound {
+		writeError(w, http.StatusNotFound, "no puzzle with id "+id)
+		return
+	}
+	if err == ErrNotReady {
+		writeError(w, http.StatusConflict, "puzzle "+id+" is not ready to play")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ws, err := Upgrade(w, r, s.CheckOrigin)
+	if err != nil {
+		return
+	}
+	session.Play(ws)
+}
+
+// Session returns the shared game of the puzzle, starting one with the pieces scattered around the board
+// if nobody is playing it yet
+func (s *Server) Session(id string) (*Session, error) {
+	s.mu.RLock()
+	session, ok := s.sessions[id]
+	job, known := s.jobs[id]
+	var status string
+	if known {
+		status = job.Status
+	}
+	s.mu.RUnlock()
+	if ok {
+		return session, nil
+	}
+	if !known {
+		return nil, ErrNotFound
+	}
+	if status != StatusDone {
+		return nil, ErrNotReady
+	}
+	//loading is done without the lock so polls and builds carry on meanwhile
+	data, err := s.Storage.Get(id, "manifest.json")
+	if err != nil {
+		return nil, err
+	}
+	manifest := jigsaw.Manifest{}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, err
+	}
+	jig := manifest.Jigsaw()
+	layout, err := scatter(jig)
+	if err != nil {
+		return nil, err
+	}
+	game, err := jigsaw.NewGame(jig, layout)
+	if err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	//someone else may have started the session while it loaded
+	if session, ok := s.sessions[id]; ok {
+		return session, nil
+	}
+	session = NewSession(id, game)
+	s.sessions[id] = session
+	return session, nil
+}
+
+// scatter lays the pieces out in a play area a few times the size of the board, growing it until they fit
+func scatter(jig jigsaw.Jigsaw) (*jigsaw.Layout, error) {
+	var err error
+	size := jig.Bounds.Size()
+	for scale := 3; scale <= 8; scale++ {
+		var layout *jigsaw.Layout
+		if layout, err = jigsaw.Scatter(jig, image.Rect(0, 0, size.X*scale, size.Y*scale), jig.Seed, false); err == nil {
+			return layout, nil
+		}
+	}
+	return nil, err
+}
+
+func parseOptions(r *http.Request) (Options, error) {
+	opts := Options{Pieces: DEFAULT_PIECES}
+	var err error
+	if v := r.FormValue("pieces"); v != "" {
+		if opts.Pieces, err = strconv.Atoi(v); err != nil || opts.Pieces < 1 {
+			return opts, errors.New("pieces should be a positive number")
+		}
+	}
+	if v := r.FormValue("rows"); v != "" {
+		if opts.Rows, err = strconv.Atoi(v); err != nil || opts.Rows < 1 {
+			return opts, errors.New("rows should be a positive number")
+		}
+	}
+	if v := r.FormValue("seed"); v != "" {
+		if opts.Seed, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return opts, errors.New("seed should be a number")
+		}
+	}
+	if v := r.FormValue("rotate"); v != "" {
+		if opts.Rotate, err = strconv.ParseBool(v); err != nil {
+			return opts, errors.New("rotate should be true or false")
+		}
+	}
+	switch opts.Mode = r.FormValue("mode"); opts.Mode {
+	case "", jigsaw.MODE_ALL, jigsaw.MODE_EDGE, jigsaw.MODE_CENTER:
+	default:
+		return opts, errors.New("mode should be all, edge or center")
+	}
+	if err := parseShape(r, &opts); err != nil {
+		return opts, err
+	}
+	return opts, nil
+}
+
+// parseShape reads the options that choose the shape of the pieces
+func parseShape(r *http.Request, opts *Options) error {
+	number := func(name string, v *float64, max float64) error {
+		s := r.FormValue(name)
+		if s == "" {
+			return nil
+		}
+		var err error
+		if *v, err = strconv.ParseFloat(s, 64); err != nil || *v < 0 || *v > max {
+			return fmt.Errorf("%s should be a number between 0 and %v", name, max)
+		}
+		return nil
+	}
+	if err := number("tab", &opts.Tab, jigsaw.MAX_TAB_SIZE); err != nil {
+		return err
+	}
+	if err := number("tabMax", &opts.TabMax, jigsaw.MAX_TAB_SIZE); err != nil {
+		return err
+	}
+	if opts.TabMax > 0 && opts.TabMax < opts.Tab {
+		return errors.New("tabMax should not be less than tab")
+	}
+	if err := number("jitter", &opts.Jitter, jigsaw.MAX_TAB_JITTER); err != nil {
+		return err
+	}
+	if err := number("wave", &opts.Wave, jigsaw.MAX_WAVE_AMPLITUDE); err != nil {
+		return err
+	}
+	opts.Joints = r.FormValue("joints")
+	if err := (jigsaw.JigsawPieceCutter{JointStyle: opts.Joints}).Validate(); err != nil {
+		return errors.New("joints should be round, square or flat")
+	}
+	switch opts.Edges = r.FormValue("edges"); opts.Edges {
+	case "", jigsaw.EDGE_STRAIGHT, jigsaw.EDGE_WAVY:
+	default:
+		return errors.New("edges should be straight or wavy")
+	}
+	switch opts.Layout = r.FormValue("layout"); opts.Layout {
+	case "", LAYOUT_GRID:
+		opts.Layout = ""
+	case LAYOUT_VORONOI, LAYOUT_HEX, LAYOUT_TRIANGLE:
+		if opts.Rows > 0 {
+			return fmt.Errorf("rows can not be given with the %s layout", opts.Layout)
+		}
+		if opts.Edges == jigsaw.EDGE_WAVY {
+			return jigsaw.ErrWavyTiling
+		}
+	default:
+		return errors.New("layout should be grid, voronoi, hex or triangle")
+	}
+	if opts.Layout == LAYOUT_VORONOI {
+		opts.Relax = jigsaw.DEFAULT_RELAX
+	}
+	if v := r.FormValue("relax"); v != "" {
+		var err error
+		if opts.Relax, err = strconv.Atoi(v); err != nil || opts.Relax < 0 {
+			return errors.New("relax should be a number that is not negative")
+		}
+	}
+	return nil
+}
+
+func pieceFile(index int) string {
+	return fmt.Sprintf("piece%d.png", index)
+}
+
+func backFile(index int) string {
+	return fmt.Sprintf("piece%d_back.png", index)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// validID reports whether id looks like one made by newID
+func validID(id string) bool {
+	if len(id) != 16 {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	writeJSON(w, code, map[string]string{"error": msg})
+}
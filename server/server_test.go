@@ -0,0 +1,245 @@
+package server_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/maleck13/jigsaw/server"
+)
+
+func upload(t *testing.T, fields map[string]string, img image.Image) *http.Request {
+	return uploadWithBack(t, fields, img, nil)
+}
+
+func uploadWithBack(t *testing.T, fields map[string]string, img, back image.Image) *http.Request {
+	body := &bytes.Buffer{}
+	form := multipart.NewWriter(body)
+	for k, v := range fields {
+		form.WriteField(k, v)
+	}
+	for name, img := range map[string]image.Image{"image": img, "back": back} {
+		if img == nil {
+			continue
+		}
+		file, err := form.CreateFormFile(name, name+".png")
+		assert.NoError(t, err)
+		assert.NoError(t, png.Encode(file, img))
+	}
+	form.Close()
+	req, err := http.NewRequest("POST", "/puzzles", body)
+	assert.NoError(t, err)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func testImage() image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	for x := 0; x < 200; x++ {
+		for y := 0; y < 200; y++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), 100, 255})
+		}
+	}
+	return img
+}
+
+func get(s http.Handler, path string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", path, nil)
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func createPuzzle(t *testing.T, s *server.Server, fields map[string]string) server.Job {
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, upload(t, fields, testImage()))
+	assert.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	job := server.Job{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	s.Wait()
+	return job
+}
+
+func testServer(t *testing.T, storage server.Storage) {
+	s := server.New(storage)
+	job := createPuzzle(t, s, map[string]string{"pieces": "6", "rows": "2", "seed": "5", "rotate": "true"})
+	assert.Equal(t, 6, job.Opts.Pieces)
+
+	rec := get(s, "/puzzles/"+job.ID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	assert.Equal(t, server.StatusDone, job.Status, job.Error)
+
+	rec = get(s, "/puzzles/"+job.ID+"/manifest")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	manifest := jigsaw.Manifest{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &manifest))
+	assert.Equal(t, 2, manifest.Rows)
+	assert.Equal(t, 3, manifest.Cols)
+	assert.Equal(t, int64(5), manifest.Seed)
+	assert.Len(t, manifest.Pieces, 6)
+
+	for _, p := range manifest.Pieces {
+		rec = get(s, p.Path)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+		_, err := png.Decode(rec.Body)
+		assert.NoError(t, err, "expected piece %d to be a png", p.Index)
+	}
+	assert.Equal(t, http.StatusNotFound, get(s, "/puzzles/"+job.ID+"/pieces/7").Code)
+}
+
+func TestServerWithMemoryStorage(t *testing.T) {
+	testServer(t, server.NewMemoryStorage())
+}
+
+func TestServerWithFileStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jigsaw")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	testServer(t, server.NewFileStorage(dir))
+}
+
+func TestServerStaysInsideStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "jigsaw")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	storage := server.NewFileStorage(filepath.Join(dir, "puzzles"))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "manifest.json"), []byte("{}"), 0644))
+	s := server.New(storage)
+	for _, path := range []string{"/puzzles/../manifest", "/puzzles/../pieces/1", "/puzzles/./manifest", "/puzzles/../play"} {
+		assert.Equal(t, http.StatusNotFound, get(s, path).Code, "expected %s not to be found", path)
+	}
+	_, err = storage.Get("..", "manifest.json")
+	assert.Equal(t, server.ErrBadName, err)
+	assert.Equal(t, server.ErrBadName, storage.Put("id", "../piece1.png", nil))
+}
+
+func TestServerFailedBuild(t *testing.T) {
+	s := server.New(server.NewMemoryStorage())
+	job := createPuzzle(t, s, map[string]string{"pieces": "7", "rows": "2"})
+	job, _ = s.Job(job.ID)
+	assert.Equal(t, server.StatusFailed, job.Status)
+	assert.NotEmpty(t, job.Error)
+	assert.Equal(t, http.StatusConflict, get(s, "/puzzles/"+job.ID+"/manifest").Code)
+}
+
+func TestServerFrameRound(t *testing.T) {
+	s := server.New(server.NewMemoryStorage())
+	job := createPuzzle(t, s, map[string]string{"pieces": "9", "mode": jigsaw.MODE_EDGE})
+	assert.Equal(t, jigsaw.MODE_EDGE, job.Opts.Mode)
+	rec := get(s, "/puzzles/"+job.ID+"/manifest")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	manifest := jigsaw.Manifest{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &manifest))
+	assert.Equal(t, jigsaw.MODE_EDGE, manifest.Mode)
+	assert.Len(t, manifest.Pieces, 8)
+	assert.Equal(t, http.StatusNotFound, get(s, "/puzzles/"+job.ID+"/pieces/5").Code, "expected no centre piece")
+}
+
+func TestServerShapeOptions(t *testing.T) {
+	s := server.New(server.NewMemoryStorage())
+	job := createPuzzle(t, s, map[string]string{"pieces": "9", "seed": "3", "joints": jigsaw.JOINT_SQUARE, "tab": "8", "tabMax": "14", "jitter": "0.2", "edges": jigsaw.EDGE_WAVY, "wave": "3"})
+	builder := job.Opts.Builder(testImage())
+	assert.Equal(t, jigsaw.JigsawPieceCutter{JointStyle: jigsaw.JOINT_SQUARE, TabSize: 8}, builder.PieceCutter)
+	assert.Equal(t, 8.0, builder.MinTabSize)
+	assert.Equal(t, 14.0, builder.MaxTabSize)
+	assert.Equal(t, 0.2, builder.TabJitter)
+	assert.Equal(t, jigsaw.EDGE_WAVY, builder.EdgeStyle)
+	assert.Equal(t, 3.0, builder.WaveAmplitude)
+	assert.Nil(t, builder.Tiling)
+	job, _ = s.Job(job.ID)
+	assert.Equal(t, server.StatusDone, job.Status, job.Error)
+	manifest := jigsaw.Manifest{}
+	assert.NoError(t, json.Unmarshal(get(s, "/puzzles/"+job.ID+"/manifest").Body.Bytes(), &manifest))
+	joint := manifest.Pieces[0].Joints[0]
+	assert.True(t, joint.Size >= 8 && joint.Size <= 14, "expected the tab size to vary, got %v", joint.Size)
+	assert.NotEmpty(t, joint.Wave, "expected wavy edges")
+
+	job = createPuzzle(t, s, map[string]string{"pieces": "12", "layout": server.LAYOUT_VORONOI})
+	assert.Equal(t, jigsaw.VoronoiTiling{Relax: jigsaw.DEFAULT_RELAX}, job.Opts.Builder(testImage()).Tiling)
+	assert.NoError(t, json.Unmarshal(get(s, "/puzzles/"+job.ID+"/manifest").Body.Bytes(), &manifest))
+	assert.NotEmpty(t, manifest.Pieces[0].Outline, "expected voronoi pieces")
+	job = createPuzzle(t, s, map[string]string{"pieces": "12", "layout": server.LAYOUT_HEX})
+	assert.Equal(t, jigsaw.HexTiling{}, job.Opts.Builder(testImage()).Tiling)
+
+	for _, fields := range []map[string]string{
+		{"joints": "zigzag"},
+		{"tab": "60"},
+		{"tab": "12", "tabMax": "8"},
+		{"jitter": "0.9"},
+		{"edges": "jagged"},
+		{"wave": "-1"},
+		{"layout": "spiral"},
+		{"layout": server.LAYOUT_HEX, "rows": "2"},
+		{"layout": server.LAYOUT_VORONOI, "edges": jigsaw.EDGE_WAVY},
+		{"layout": server.LAYOUT_VORONOI, "relax": "-1"},
+	} {
+		rec := httptest.NewRecorder()
+		s.ServeHTTP(rec, upload(t, fields, testImage()))
+		assert.Equal(t, http.StatusBadRequest, rec.Code, "expected %v to be refused", fields)
+	}
+}
+
+func TestServerDoubleSided(t *testing.T) {
+	s := server.New(server.NewMemoryStorage())
+	back := image.NewRGBA(image.Rect(0, 0, 200, 200))
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, uploadWithBack(t, map[string]string{"pieces": "4", "rows": "2"}, testImage(), back))
+	assert.Equal(t, http.StatusAccepted, rec.Code, rec.Body.String())
+	job := server.Job{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &job))
+	s.Wait()
+
+	rec = get(s, "/puzzles/"+job.ID+"/manifest")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	manifest := jigsaw.Manifest{}
+	assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &manifest))
+	assert.Len(t, manifest.Pieces, 4)
+	for _, p := range manifest.Pieces {
+		assert.Equal(t, fmt.Sprintf("/puzzles/%s/pieces/%d/back", job.ID, p.Index), p.BackPath)
+		rec = get(s, p.BackPath)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "image/png", rec.Header().Get("Content-Type"))
+		img, err := png.Decode(rec.Body)
+		assert.NoError(t, err)
+		front, err := png.Decode(get(s, p.Path).Body)
+		assert.NoError(t, err)
+		assert.Equal(t, front.Bounds(), img.Bounds(), "expected the back to fit the front")
+	}
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, uploadWithBack(t, map[string]string{"pieces": "4", "rows": "2"}, testImage(), image.NewRGBA(image.Rect(0, 0, 100, 200))))
+	assert.Equal(t, http.StatusBadRequest, rec.Code, "expected a back of the wrong size to be refused")
+}
+
+func TestServerBadRequests(t *testing.T) {
+	s := server.New(server.NewMemoryStorage())
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, upload(t, map[string]string{"pieces": "6"}, nil))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, upload(t, map[string]string{"pieces": "lots"}, testImage()))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	rec = httptest.NewRecorder()
+	s.ServeHTTP(rec, upload(t, map[string]string{"mode": "middle"}, testImage()))
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+
+	assert.Equal(t, http.StatusNotFound, get(s, "/puzzles/unknown").Code)
+	assert.Equal(t, http.StatusNotFound, get(s, "/puzzles/0123456789abcdef/manifest").Code, "expected no files for an unknown puzzle")
+	assert.Equal(t, http.StatusNotFound, get(s, "/other").Code)
+}
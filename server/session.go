@@ -0,0 +1,260 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"sync"
+
+	"github.com/maleck13/jigsaw"
+)
+
+// messages sent by players
+const (
+	MessageGrab   = "grab"
+	MessageMove   = "move"
+	MessageRotate = "rotate"
+	MessageDrop   = "drop"
+)
+
+// messages sent to players
+const (
+	MessageSnapshot = "snapshot"
+	MessageJoined   = "joined"
+	MessageLeft     = "left"
+	MessageGrabbed  = "grabbed"
+	MessageMoved    = "moved"
+	MessageRotated  = "rotated"
+	MessageDropped  = "dropped"
+	MessageReleased = "released"
+	MessageError    = "error"
+)
+
+// how many messages can be waiting to go to a player before they are treated as gone
+const PLAYER_SEND_BUFFER = 256
+
+// Message is the json sent over a play websocket in both directions. Pieces holds the state of every piece
+// that changed, or of every piece in a snapshot, Locks which player holds which piece and Stats how every
+// player is doing, sent with snapshots and drops
+type Message struct {
+	Type     string              `json:"type"`
+	Player   string              `json:"player,omitempty"`
+	Piece    int                 `json:"piece,omitempty"`
+	Position *image.Point        `json:"position,omitempty"`
+	Degrees  int                 `json:"degrees,omitempty"`
+	Snapped  bool                `json:"snapped,omitempty"`
+	Complete bool                `json:"complete,omitempty"`
+	Pieces   []jigsaw.PieceState `json:"pieces,omitempty"`
+	Locks    map[int]string      `json:"locks,omitempty"`
+	Stats    []jigsaw.Stats      `json:"stats,omitempty"`
+	Error    string              `json:"error,omitempty"`
+}
+
+type player struct {
+	id   string
+	ws   *WebSocket
+	send chan []byte
+}
+
+// Session is one game of a jigsaw shared by every player connected to it. A player has to hold a piece to
+// move it and only one player can hold a piece, or any piece joined to it, at a time
+type Session struct {
+	ID         string
+	mu         sync.Mutex
+	game       *jigsaw.Game
+	players    map[string]*player
+	locks      map[int]string
+	nextPlayer int
+}
+
+func NewSession(id string, game *jigsaw.Game) *Session {
+	return &Session{ID: id, game: game, players: make(map[string]*player), locks: make(map[int]string)}
+}
+
+// Play adds a player on the websocket to the session, sends them the current state of the game and then
+// handles their messages until they disconnect
+func (s *Session) Play(ws *WebSocket) {
+	p := s.join(ws)
+	go func() {
+		for data := range p.send {
+			if err := ws.WriteMessage(data); err != nil {
+				ws.Close()
+				return
+			}
+		}
+	}()
+	for {
+		data, err := ws.ReadMessage()
+		if err != nil {
+			break
+		}
+		msg := Message{}
+		if err := json.Unmarshal(data, &msg); err != nil {
+			s.reply(p, errors.New("could not read message "+err.Error()))
+			continue
+		}
+		s.handle(p, msg)
+	}
+	s.leave(p)
+	ws.Close()
+}
+
+// Players returns how many players are connected
+func (s *Session) Players() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.players)
+}
+
+func (s *Session) join(ws *WebSocket) *player {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextPlayer++
+	p := &player{id: fmt.Sprintf("player%d", s.nextPlayer), ws: ws, send: make(chan []byte, PLAYER_SEND_BUFFER)}
+	s.players[p.id] = p
+	s.game.Join(p.id)
+	s.sendTo(p, s.snapshot(p))
+	for _, other := range s.players {
+		if other != p {
+			s.sendTo(other, Message{Type: MessageJoined, Player: p.id})
+		}
+	}
+	return p
+}
+
+func (s *Session) leave(p *player) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.players[p.id]; !ok {
+		return
+	}
+	delete(s.players, p.id)
+	close(p.send)
+	s.game.Leave(p.id)
+	for piece, holder := range s.locks {
+		if holder == p.id {
+			delete(s.locks, piece)
+			s.broadcast(Message{Type: MessageReleased, Player: p.id, Piece: piece})
+		}
+	}
+	s.broadcast(Message{Type: MessageLeft, Player: p.id})
+}
+
+func (s *Session) handle(p *player, msg Message) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch msg.Type {
+	case MessageGrab, MessageMove, MessageRotate, MessageDrop:
+	default:
+		s.sendTo(p, Message{Type: MessageError, Error: "unknown message type " + msg.Type})
+		return
+	}
+	if err := s.grab(p, msg.Piece); err != nil {
+		s.sendTo(p, Message{Type: MessageError, Error: err.Error(), Piece: msg.Piece})
+		return
+	}
+	var err error
+	mover := s.game.Player(p.id)
+	out := Message{Player: p.id, Piece: msg.Piece}
+	switch msg.Type {
+	case MessageGrab:
+		out.Type = MessageGrabbed
+	case MessageMove:
+		if msg.Position == nil {
+			err = errors.New("move needs a position")
+			break
+		}
+		out.Type = MessageMoved
+		err = mover.Move(msg.Piece, *msg.Position)
+	case MessageRotate:
+		out.Type = MessageRotated
+		out.Degrees = msg.Degrees
+		err = mover.Rotate(msg.Piece, msg.Degrees)
+	case MessageDrop:
+		out.Type = MessageDropped
+		//snapping onto a group another player holds would move it out from under them
+		out.Snapped, err = mover.DropAvoiding(msg.Piece, func(piece int) bool {
+			holder, ok := s.locks[piece]
+			return ok && holder != p.id
+		})
+		out.Complete = s.game.Complete()
+		out.Stats = s.game.AllStats()
+		delete(s.locks, msg.Piece)
+	}
+	if err != nil {
+		s.sendTo(p, Message{Type: MessageError, Error: err.Error(), Piece: msg.Piece})
+		return
+	}
+	if out.Type != MessageGrabbed {
+		out.Pieces = s.groupStates(msg.Piece)
+	}
+	s.broadcast(out)
+}
+
+// grab makes the player the holder of the piece, letting go of anything else they held. It fails when
+// another player holds the piece or a piece joined to it
+func (s *Session) grab(p *player, piece int) error {
+	group, err := s.game.Group(piece)
+	if err != nil {
+		return err
+	}
+	for _, m := range group {
+		if holder, ok := s.locks[m]; ok && holder != p.id {
+			return errors.New("piece is held by " + holder)
+		}
+	}
+	for held, holder := range s.locks {
+		if holder == p.id && held != piece {
+			delete(s.locks, held)
+		}
+	}
+	s.locks[piece] = p.id
+	return nil
+}
+
+func (s *Session) groupStates(piece int) []jigsaw.PieceState {
+	group, _ := s.game.Group(piece)
+	states := make([]jigsaw.PieceState, len(group))
+	for i, m := range group {
+		states[i], _ = s.game.Piece(m)
+	}
+	return states
+}
+
+func (s *Session) snapshot(p *player) Message {
+	locks := make(map[int]string, len(s.locks))
+	for piece, holder := range s.locks {
+		locks[piece] = holder
+	}
+	return Message{Type: MessageSnapshot, Player: p.id, Pieces: s.game.Pieces(), Locks: locks, Complete: s.game.Complete(), Stats: s.game.AllStats()}
+}
+
+func (s *Session) reply(p *player, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sendTo(p, Message{Type: MessageError, Error: err.Error()})
+}
+
+func (s *Session) broadcast(msg Message) {
+	for _, p := range s.players {
+		s.sendTo(p, msg)
+	}
+}
+
+// sendTo queues the message for the player. A player that is not keeping up is disconnected rather than
+// holding up everyone else
+func (s *Session) sendTo(p *player, msg Message) {
+	if _, ok := s.players[p.id]; !ok {
+		return
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return
+	}
+	select {
+	case p.send <- data:
+	default:
+		p.ws.conn.Close()
+	}
+}
@@ -0,0 +1,188 @@
+package server_test
+
+import (
+	"encoding/json"
+	"image"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/maleck13/jigsaw/server"
+)
+
+func dial(t *testing.T, ts *httptest.Server, id string) *server.WebSocket {
+	ws, err := server.Dial(strings.Replace(ts.URL, "http://", "ws://", 1) + "/puzzles/" + id + "/play")
+	assert.NoError(t, err, "did not expect an error joining the game")
+	ws.SetDeadline(time.Now().Add(10 * time.Second))
+	return ws
+}
+
+func send(t *testing.T, ws *server.WebSocket, msg server.Message) {
+	data, _ := json.Marshal(msg)
+	assert.NoError(t, ws.WriteMessage(data))
+}
+
+// next reads messages until one of the given type arrives
+func next(t *testing.T, ws *server.WebSocket, msgType string) server.Message {
+	for {
+		data, err := ws.ReadMessage()
+		if err != nil {
+			t.Fatalf("waiting for %s: %s", msgType, err)
+		}
+		msg := server.Message{}
+		assert.NoError(t, json.Unmarshal(data, &msg))
+		if msg.Type == msgType {
+			return msg
+		}
+	}
+}
+
+func stateOf(states []jigsaw.PieceState, index int) jigsaw.PieceState {
+	for _, s := range states {
+		if s.Index == index {
+			return s
+		}
+	}
+	return jigsaw.PieceState{}
+}
+
+func TestSessionMultiplayer(t *testing.T) {
+	s := server.New(server.NewMemoryStorage())
+	job := createPuzzle(t, s, map[string]string{"pieces": "4", "rows": "2"})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+
+	manifest := jigsaw.Manifest{}
+	assert.NoError(t, json.Unmarshal(get(s, "/puzzles/"+job.ID+"/manifest").Body.Bytes(), &manifest))
+
+	alice := dial(t, ts, job.ID)
+	defer alice.Close()
+	snapshot := next(t, alice, server.MessageSnapshot)
+	assert.Equal(t, "player1", snapshot.Player)
+	assert.Len(t, snapshot.Pieces, 4)
+
+	bob := dial(t, ts, job.ID)
+	defer bob.Close()
+	next(t, bob, server.MessageSnapshot)
+	assert.Equal(t, "player2", next(t, alice, server.MessageJoined).Player)
+
+	//alice holds piece 2 so bob cannot have it
+	send(t, alice, server.Message{Type: server.MessageGrab, Piece: 2})
+	assert.Equal(t, "player1", next(t, bob, server.MessageGrabbed).Player)
+	send(t, bob, server.Message{Type: server.MessageMove, Piece: 2, Position: &image.Point{0, 0}})
+	assert.Contains(t, next(t, bob, server.MessageError).Error, "held by player1")
+
+	//alice drops piece 2 right of piece 1 and it snaps
+	one := stateOf(snapshot.Pieces, 1)
+	offset := manifest.Pieces[1].Bounds.Min.Sub(manifest.Pieces[0].Bounds.Min)
+	target := one.Position.Add(offset).Add(image.Pt(3, -2))
+	send(t, alice, server.Message{Type: server.MessageMove, Piece: 2, Position: &target})
+	moved := next(t, bob, server.MessageMoved)
+	assert.Equal(t, target, stateOf(moved.Pieces, 2).Position)
+	send(t, alice, server.Message{Type: server.MessageDrop, Piece: 2})
+	dropped := next(t, bob, server.MessageDropped)
+	assert.True(t, dropped.Snapped, "expected piece to snap")
+	assert.Len(t, dropped.Pieces, 2)
+	assert.Equal(t, one.Position.Add(offset), stateOf(dropped.Pieces, 2).Position)
+	assert.Len(t, dropped.Stats, 2)
+	assert.Equal(t, "player1", dropped.Stats[0].Player)
+	assert.Equal(t, 1, dropped.Stats[0].Snaps)
+
+	//once dropped bob can take the group
+	send(t, bob, server.Message{Type: server.MessageGrab, Piece: 1})
+	assert.Equal(t, "player2", next(t, bob, server.MessageGrabbed).Player)
+
+	//a late joiner gets the current state and the locks
+	carol := dial(t, ts, job.ID)
+	defer carol.Close()
+	late := next(t, carol, server.MessageSnapshot)
+	assert.Equal(t, one.Position.Add(offset), stateOf(late.Pieces, 2).Position)
+	assert.Equal(t, map[int]string{1: "player2"}, late.Locks)
+
+	//bob leaving lets go of his piece
+	bob.Close()
+	released := next(t, carol, server.MessageReleased)
+	assert.Equal(t, 1, released.Piece)
+}
+
+func TestPlayUnknownPuzzle(t *testing.T) {
+	s := server.New(server.NewMemoryStorage())
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	resp, err := http.Get(ts.URL + "/puzzles/missing/play")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+// slowStorage holds up reading manifests until it is let go
+type slowStorage struct {
+	*server.MemoryStorage
+	reading chan bool
+	release chan bool
+}
+
+func (ss slowStorage) Get(id, name string) ([]byte, error) {
+	if name == "manifest.json" {
+		ss.reading <- true
+		<-ss.release
+	}
+	return ss.MemoryStorage.Get(id, name)
+}
+
+func TestSessionLoadsWithoutBlocking(t *testing.T) {
+	storage := slowStorage{server.NewMemoryStorage(), make(chan bool, 2), make(chan bool)}
+	s := server.New(storage)
+	job := createPuzzle(t, s, map[string]string{"pieces": "4", "rows": "2"})
+
+	sessions := make(chan *server.Session, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			session, err := s.Session(job.ID)
+			assert.NoError(t, err)
+			sessions <- session
+		}()
+	}
+	<-storage.reading
+	<-storage.reading
+	//the puzzle can be polled while its session loads
+	assert.Equal(t, http.StatusOK, get(s, "/puzzles/"+job.ID).Code)
+	close(storage.release)
+	a, b := <-sessions, <-sessions
+	assert.True(t, a == b, "expected both players to share one session")
+}
+
+func TestSessionDropDoesNotSnapOntoHeldPieces(t *testing.T) {
+	s := server.New(server.NewMemoryStorage())
+	job := createPuzzle(t, s, map[string]string{"pieces": "4", "rows": "2"})
+	ts := httptest.NewServer(s)
+	defer ts.Close()
+	manifest := jigsaw.Manifest{}
+	assert.NoError(t, json.Unmarshal(get(s, "/puzzles/"+job.ID+"/manifest").Body.Bytes(), &manifest))
+
+	alice := dial(t, ts, job.ID)
+	defer alice.Close()
+	snapshot := next(t, alice, server.MessageSnapshot)
+	bob := dial(t, ts, job.ID)
+	defer bob.Close()
+	next(t, bob, server.MessageSnapshot)
+
+	//bob holds piece 1 while alice drops piece 2 where it belongs next to it
+	send(t, bob, server.Message{Type: server.MessageGrab, Piece: 1})
+	assert.Equal(t, "player2", next(t, alice, server.MessageGrabbed).Player)
+	one := stateOf(snapshot.Pieces, 1)
+	target := one.Position.Add(manifest.Pieces[1].Bounds.Min.Sub(manifest.Pieces[0].Bounds.Min))
+	send(t, alice, server.Message{Type: server.MessageMove, Piece: 2, Position: &target})
+	next(t, alice, server.MessageMoved)
+	send(t, alice, server.Message{Type: server.MessageDrop, Piece: 2})
+	dropped := next(t, bob, server.MessageDropped)
+	assert.False(t, dropped.Snapped, "expected no snap onto the piece bob holds")
+	assert.Len(t, dropped.Pieces, 1)
+
+	//once alice has let go bob's drop can join the two
+	send(t, bob, server.Message{Type: server.MessageDrop, Piece: 1})
+	assert.True(t, next(t, bob, server.MessageDropped).Snapped, "expected bob's drop to snap")
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var ErrNotFound = errors.New("not found")
+
+var ErrBadName = errors.New("bad puzzle id or file name")
+
+// Storage keeps the files that make up each puzzle, the manifest and the piece images, keyed by the
+// puzzle id and a file name
+type Storage interface {
+	Put(id, name string, data []byte) error
+	Get(id, name string) ([]byte, error)
+}
+
+// FileStorage keeps each puzzle in its own directory under Dir
+type FileStorage struct {
+	Dir string
+}
+
+func NewFileStorage(dir string) *FileStorage {
+	return &FileStorage{Dir: dir}
+}
+
+// path is where the file is kept. Ids and names are single path elements so nothing outside Dir can be reached
+func (fs *FileStorage) path(id, name string) (string, error) {
+	for _, part := range []string{id, name} {
+		if part == "" || part == "." || part == ".." || strings.ContainsAny(part, `/\`) {
+			return "", ErrBadName
+		}
+	}
+	return filepath.Join(fs.Dir, id, name), nil
+}
+
+func (fs *FileStorage) Put(id, name string, data []byte) error {
+	path, err := fs.path(id, name)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, 0644)
+}
+
+func (fs *FileStorage) Get(id, name string) ([]byte, error) {
+	path, err := fs.path(id, name)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+// MemoryStorage keeps everything in memory, it is intended for tests
+type MemoryStorage struct {
+	mu    sync.RWMutex
+	files map[string][]byte
+}
+
+func NewMemoryStorage() *MemoryStorage {
+	return &MemoryStorage{files: make(map[string][]byte)}
+}
+
+func (ms *MemoryStorage) Put(id, name string, data []byte) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.files[id+"/"+name] = data
+	return nil
+}
+
+func (ms *MemoryStorage) Get(id, name string) ([]byte, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	data, ok := ms.files[id+"/"+name]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return data, nil
+}
@@ -0,0 +1,311 @@
+package server
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// just enough of RFC 6455 to exchange json text messages with browsers and Go clients
+
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const MAX_MESSAGE_SIZE = 1 << 20
+
+const (
+	opText  = 0x1
+	opClose = 0x8
+	opPing  = 0x9
+	opPong  = 0xa
+)
+
+// statusUpgradeRequired refuses a handshake for a version of the protocol other than 13
+const statusUpgradeRequired = 426
+
+// close status codes sent before dropping a connection that broke the protocol
+const (
+	closeProtocolError = 1002
+	closeTooLarge      = 1009
+)
+
+var (
+	ErrMessageTooLarge = errors.New("websocket message is too large")
+	ErrUnmaskedFrame   = errors.New("websocket client frames must be masked and server frames must not be")
+)
+
+// WebSocket is one end of a websocket connection
+type WebSocket struct {
+	conn   net.Conn
+	r      *bufio.Reader
+	client bool
+	wmu    sync.Mutex
+}
+
+// Upgrade takes over the http connection and completes the websocket handshake. Only version 13 of the
+// protocol is spoken and the request has to pass checkOrigin, SameOrigin when it is nil
+func Upgrade(w http.ResponseWriter, r *http.Request, checkOrigin func(r *http.Request) bool) (*WebSocket, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || !headerContains(r.Header, "Connection", "upgrade") || key == "" {
+		http.Error(w, "expected a websocket upgrade", http.StatusBadRequest)
+		return nil, errors.New("request is not a websocket upgrade")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		w.Header().Set("Sec-WebSocket-Version", "13")
+		http.Error(w, "only websocket version 13 is supported", statusUpgradeRequired)
+		return nil, errors.New("unsupported websocket version " + r.Header.Get("Sec-WebSocket-Version"))
+	}
+	if checkOrigin == nil {
+		checkOrigin = SameOrigin
+	}
+	if !checkOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return nil, errors.New("websocket origin " + r.Header.Get("Origin") + " is not allowed")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("response does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &WebSocket{conn: conn, r: rw.Reader}, nil
+}
+
+// SameOrigin allows pages served from the host the request was made to, and clients that are not browsers
+// and so send no Origin
+func SameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
+
+// Dial opens a websocket to a ws:// url
+func Dial(rawurl string) (*WebSocket, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "ws" {
+		return nil, errors.New("only ws:// urls are supported")
+	}
+	host := u.Host
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host += ":80"
+	}
+	conn, err := net.Dial("tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+	req, err := http.NewRequest("GET", rawurl, nil)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		conn.Close()
+		return nil, errors.New("websocket handshake failed with " + resp.Status)
+	}
+	return &WebSocket{conn: conn, r: br, client: true}, nil
+}
+
+// ReadMessage returns the next text or binary message. Pings are answered while waiting and io.EOF is
+// returned once the other end closes the connection
+func (ws *WebSocket) ReadMessage() ([]byte, error) {
+	var message []byte
+	for {
+		fin, op, payload, err := ws.readFrame()
+		if err != nil {
+			ws.fail(err)
+			return nil, err
+		}
+		switch op {
+		case opClose:
+			ws.writeFrame(opClose, payload)
+			return nil, io.EOF
+		case opPing:
+			if err := ws.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+			continue
+		case opPong:
+			continue
+		}
+		message = append(message, payload...)
+		if len(message) > MAX_MESSAGE_SIZE {
+			ws.fail(ErrMessageTooLarge)
+			return nil, ErrMessageTooLarge
+		}
+		if fin {
+			return message, nil
+		}
+	}
+}
+
+// WriteMessage sends a text message, it is safe to call from more than one goroutine
+func (ws *WebSocket) WriteMessage(data []byte) error {
+	return ws.writeFrame(opText, data)
+}
+
+// SetDeadline sets when reads and writes on the connection give up
+func (ws *WebSocket) SetDeadline(t time.Time) error {
+	return ws.conn.SetDeadline(t)
+}
+
+func (ws *WebSocket) Close() error {
+	ws.writeFrame(opClose, nil)
+	return ws.conn.Close()
+}
+
+// fail tells the other end why the connection is being dropped when it broke the protocol
+func (ws *WebSocket) fail(err error) {
+	status := make([]byte, 2)
+	switch err {
+	case ErrUnmaskedFrame:
+		binary.BigEndian.PutUint16(status, closeProtocolError)
+	case ErrMessageTooLarge:
+		binary.BigEndian.PutUint16(status, closeTooLarge)
+	default:
+		return
+	}
+	ws.writeFrame(opClose, status)
+}
+
+// readFrame reads one frame, refusing one whose masking is wrong for its sender or whose length is past
+// MAX_MESSAGE_SIZE before any of its payload is read
+func (ws *WebSocket) readFrame() (bool, byte, []byte, error) {
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(ws.r, header); err != nil {
+		return false, 0, nil, err
+	}
+	fin, op := header[0]&0x80 != 0, header[0]&0x0f
+	masked, length := header[1]&0x80 != 0, uint64(header[1]&0x7f)
+	//RFC 6455 5.1, only frames from a client are masked
+	if masked != !ws.client {
+		return false, 0, nil, ErrUnmaskedFrame
+	}
+	switch length {
+	case 126:
+		ext := make([]byte, 2)
+		if _, err := io.ReadFull(ws.r, ext); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext))
+	case 127:
+		ext := make([]byte, 8)
+		if _, err := io.ReadFull(ws.r, ext); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext)
+	}
+	if length > MAX_MESSAGE_SIZE {
+		return false, 0, nil, ErrMessageTooLarge
+	}
+	mask := make([]byte, 4)
+	if masked {
+		if _, err := io.ReadFull(ws.r, mask); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(ws.r, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, op, payload, nil
+}
+
+// writeFrame sends data as a single frame. Frames sent by a client have to be masked
+func (ws *WebSocket) writeFrame(op byte, data []byte) error {
+	frame := []byte{0x80 | op}
+	maskBit := byte(0)
+	if ws.client {
+		maskBit = 0x80
+	}
+	switch {
+	case len(data) < 126:
+		frame = append(frame, maskBit|byte(len(data)))
+	case len(data) <= 0xffff:
+		frame = append(frame, maskBit|126, 0, 0)
+		binary.BigEndian.PutUint16(frame[2:], uint16(len(data)))
+	default:
+		frame = append(frame, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(frame[2:], uint64(len(data)))
+	}
+	payload := data
+	if ws.client {
+		mask := make([]byte, 4)
+		if _, err := rand.Read(mask); err != nil {
+			return err
+		}
+		frame = append(frame, mask...)
+		payload = make([]byte, len(data))
+		for i := range data {
+			payload[i] = data[i] ^ mask[i%4]
+		}
+	}
+	ws.wmu.Lock()
+	defer ws.wmu.Unlock()
+	_, err := ws.conn.Write(append(frame, payload...))
+	return err
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func headerContains(h http.Header, name, value string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, s := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
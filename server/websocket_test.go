@@ -0,0 +1,117 @@
+package server_test
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+	"github.com/maleck13/jigsaw/server"
+)
+
+// rawClient completes the handshake by hand so the test can send frames a real client never would. The
+// server's read error comes back on the channel. The returned func shuts everything down
+func rawClient(t *testing.T) (net.Conn, *bufio.Reader, chan error, func()) {
+	errs := make(chan error, 1)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ws, err := server.Upgrade(w, r, nil)
+		if err != nil {
+			errs <- err
+			return
+		}
+		defer ws.Close()
+		_, err = ws.ReadMessage()
+		errs <- err
+	}))
+	conn, err := net.Dial("tcp", strings.TrimPrefix(ts.URL, "http://"))
+	if err != nil {
+		ts.Close()
+		t.Fatalf("dialling: %s", err)
+	}
+	done := func() {
+		conn.Close()
+		ts.Close()
+	}
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	io.WriteString(conn, "GET / HTTP/1.1\r\nHost: test\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n")
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			done()
+			t.Fatalf("reading the handshake: %s", err)
+		}
+		if line == "\r\n" {
+			break
+		}
+	}
+	return conn, r, errs, done
+}
+
+func closeStatus(t *testing.T, r *bufio.Reader) []byte {
+	frame := make([]byte, 4)
+	_, err := io.ReadFull(r, frame)
+	assert.NoError(t, err, "expected a close frame")
+	assert.Equal(t, byte(0x88), frame[0], "expected a close frame")
+	return frame[2:]
+}
+
+func TestWebSocketRefusesUnmaskedClientFrames(t *testing.T) {
+	conn, r, errs, done := rawClient(t)
+	defer done()
+	conn.Write([]byte{0x81, 0x02, 'h', 'i'})
+	assert.Equal(t, server.ErrUnmaskedFrame, <-errs)
+	assert.Equal(t, []byte{0x03, 0xea}, closeStatus(t, r), "expected a protocol error")
+}
+
+func TestWebSocketRefusesOversizedFrames(t *testing.T) {
+	conn, r, errs, done := rawClient(t)
+	defer done()
+	//a masked frame claiming a terabyte, with none of it sent
+	conn.Write([]byte{0x81, 0xff, 0, 0, 1, 0, 0, 0, 0, 0, 1, 2, 3, 4})
+	assert.Equal(t, server.ErrMessageTooLarge, <-errs)
+	assert.Equal(t, []byte{0x03, 0xf1}, closeStatus(t, r), "expected the message to be too big")
+}
+
+func handshake(s *server.Server, id string, headers map[string]string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest("GET", "/puzzles/"+id+"/play", nil)
+	req.Host = "jigsaw.test"
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestWebSocketHandshakeChecks(t *testing.T) {
+	s := server.New(server.NewMemoryStorage())
+	job := createPuzzle(t, s, map[string]string{"pieces": "4", "rows": "2"})
+
+	rec := handshake(s, job.ID, map[string]string{"Sec-WebSocket-Version": "8"})
+	assert.Equal(t, 426, rec.Code, "expected other versions to be refused")
+	assert.Equal(t, "13", rec.Header().Get("Sec-WebSocket-Version"))
+	assert.Equal(t, 426, handshake(s, job.ID, nil).Code, "expected a missing version to be refused")
+
+	rec = handshake(s, job.ID, map[string]string{"Sec-WebSocket-Version": "13", "Origin": "http://elsewhere.test"})
+	assert.Equal(t, http.StatusForbidden, rec.Code, "expected another site to be refused")
+
+	s.CheckOrigin = func(r *http.Request) bool { return r.Header.Get("Origin") == "http://elsewhere.test" }
+	rec = handshake(s, job.ID, map[string]string{"Sec-WebSocket-Version": "13", "Origin": "http://jigsaw.test"})
+	assert.Equal(t, http.StatusForbidden, rec.Code, "expected CheckOrigin to decide")
+
+	//a recorder cannot be hijacked so passing every check ends in a 500
+	rec = handshake(s, job.ID, map[string]string{"Sec-WebSocket-Version": "13", "Origin": "http://elsewhere.test"})
+	assert.Equal(t, http.StatusInternalServerError, rec.Code)
+
+	assert.True(t, server.SameOrigin(&http.Request{Host: "jigsaw.test", Header: http.Header{"Origin": {"https://jigsaw.test"}}}))
+	assert.True(t, server.SameOrigin(&http.Request{Host: "jigsaw.test", Header: http.Header{}}), "expected clients without an origin to be allowed")
+}
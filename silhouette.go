@@ -0,0 +1,232 @@
+package jigsaw
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/llgcode/draw2d"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/llgcode/draw2d/draw2dimg"
+)
+
+// LoadSilhouette loads the shape of a whimsy. An svg file has its paths filled in, anything else is
+// loaded as an image whose alpha gives the shape
+func LoadSilhouette(path string) (image.Image, error) {
+	if strings.ToLower(filepath.Ext(path)) != ".svg" {
+		return LoadImage(path)
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	img, err := DecodeSVGSilhouette(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode %s: %s", path, err)
+	}
+	return img, nil
+}
+
+// DecodeSVGSilhouette fills in every path of an svg. The image is the svg's width and height, or the size
+// of its viewBox without them. Paths can move, draw lines and draw quadratic and cubic curves but not arcs,
+// and nothing can be transformed
+func DecodeSVGSilhouette(r io.Reader) (image.Image, error) {
+	decoder := xml.NewDecoder(r)
+	var size, origin, scale vec
+	var paths []string
+	seenSVG := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		attrs := map[string]string{}
+		for _, a := range start.Attr {
+			attrs[a.Name.Local] = a.Value
+		}
+		if _, ok := attrs["transform"]; ok {
+			return nil, fmt.Errorf("svg transforms are not supported")
+		}
+		switch {
+		case start.Name.Local == "svg" && !seenSVG:
+			seenSVG = true
+			if size, origin, scale, err = svgViewport(attrs); err != nil {
+				return nil, err
+			}
+		case start.Name.Local == "path":
+			paths = append(paths, attrs["d"])
+		}
+	}
+	if !seenSVG {
+		return nil, fmt.Errorf("not an svg")
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("svg has no paths")
+	}
+	img := image.NewRGBA(image.Rect(0, 0, int(size.X+0.5), int(size.Y+0.5)))
+	if img.Bounds().Empty() {
+		return nil, fmt.Errorf("svg has no size")
+	}
+	gc := draw2dimg.NewGraphicContext(img)
+	gc.SetFillColor(color.Black)
+	gc.SetFillRule(draw2d.FillRuleWinding)
+	place := func(p vec) vec { return vec{(p.X - origin.X) * scale.X, (p.Y - origin.Y) * scale.Y} }
+	for _, d := range paths {
+		path, err := parseSVGPath(d, place)
+		if err != nil {
+			return nil, err
+		}
+		gc.Fill(path)
+	}
+	return img, nil
+}
+
+// svgViewport works out the size of the svg and how to get from its viewBox to that size
+func svgViewport(attrs map[string]string) (size, origin, scale vec, err error) {
+	scale = vec{1, 1}
+	if box := strings.Fields(strings.Replace(attrs["viewBox"], ",", " ", -1)); len(box) == 4 {
+		var v [4]float64
+		for i, f := range box {
+			if v[i], err = strconv.ParseFloat(f, 64); err != nil {
+				return size, origin, scale, fmt.Errorf("bad svg viewBox %q", attrs["viewBox"])
+			}
+		}
+		origin, size = vec{v[0], v[1]}, vec{v[2], v[3]}
+	}
+	width, height := svgLength(attrs["width"]), svgLength(attrs["height"])
+	if width > 0 && height > 0 {
+		if size.X > 0 && size.Y > 0 {
+			scale = vec{width / size.X, height / size.Y}
+		}
+		size = vec{width, height}
+	}
+	return size, origin, scale, nil
+}
+
+func svgLength(s string) float64 {
+	v, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(s), "px"), 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+var svgPathToken = regexp.MustCompile(`[A-Za-z]|[-+]?(?:\d+\.?\d*|\.\d+)(?:[eE][-+]?\d+)?`)
+
+// svgPathArgs is how many numbers each path command takes
+var svgPathArgs = map[byte]int{'M': 2, 'L': 2, 'H': 1, 'V': 1, 'C': 6, 'Q': 4, 'Z': 0}
+
+// parseSVGPath turns the d attribute of an svg path into a path, place moving each point into the image
+func parseSVGPath(d string, place func(vec) vec) (*draw2d.Path, error) {
+	var tokens []string
+	last := 0
+	for _, at := range svgPathToken.FindAllStringIndex(d, -1) {
+		if strings.Trim(d[last:at[0]], " \t\r\n,") != "" {
+			return nil, fmt.Errorf("bad svg path %q", d)
+		}
+		tokens = append(tokens, d[at[0]:at[1]])
+		last = at[1]
+	}
+	if strings.Trim(d[last:], " \t\r\n,") != "" {
+		return nil, fmt.Errorf("bad svg path %q", d)
+	}
+	path := &draw2d.Path{}
+	var command byte
+	var current, start vec
+	for i := 0; i < len(tokens); {
+		if c := tokens[i][0]; (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') {
+			command = c
+			i++
+			if _, ok := svgPathArgs[upper(c)]; !ok {
+				return nil, fmt.Errorf("svg path command %q is not supported", c)
+			}
+			if upper(c) == 'Z' {
+				path.Close()
+				current = start
+			}
+			continue
+		}
+		if command == 0 || upper(command) == 'Z' {
+			return nil, fmt.Errorf("bad svg path %q", d)
+		}
+		n := svgPathArgs[upper(command)]
+		if i+n > len(tokens) {
+			return nil, fmt.Errorf("svg path %q ends part way through a command", d)
+		}
+		args := make([]float64, n)
+		for k := range args {
+			v, err := strconv.ParseFloat(tokens[i+k], 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad svg path %q", d)
+			}
+			args[k] = v
+		}
+		i += n
+		relative := command != upper(command)
+		point := func(k int) vec {
+			p := vec{args[k], args[k+1]}
+			if relative {
+				p = p.add(current)
+			}
+			return p
+		}
+		switch upper(command) {
+		case 'M':
+			current, start = point(0), point(0)
+			p := place(current)
+			path.MoveTo(p.X, p.Y)
+			//any more points after a move are lines
+			command -= 'M' - 'L'
+		case 'L':
+			current = point(0)
+			p := place(current)
+			path.LineTo(p.X, p.Y)
+		case 'H':
+			if relative {
+				args[0] += current.X
+			}
+			current.X = args[0]
+			p := place(current)
+			path.LineTo(p.X, p.Y)
+		case 'V':
+			if relative {
+				args[0] += current.Y
+			}
+			current.Y = args[0]
+			p := place(current)
+			path.LineTo(p.X, p.Y)
+		case 'C':
+			c1, c2 := place(point(0)), place(point(2))
+			current = point(4)
+			p := place(current)
+			path.CubicCurveTo(c1.X, c1.Y, c2.X, c2.Y, p.X, p.Y)
+		case 'Q':
+			c := place(point(0))
+			current = point(2)
+			p := place(current)
+			path.QuadCurveTo(c.X, c.Y, p.X, p.Y)
+		}
+	}
+	return path, nil
+}
+
+func upper(c byte) byte {
+	if c >= 'a' && c <= 'z' {
+		return c - 'a' + 'A'
+	}
+	return c
+}
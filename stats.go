@@ -0,0 +1,136 @@
+package jigsaw
+
+import (
+	"image"
+	"sort"
+	"time"
+)
+
+// SOLO_PLAYER is who the moves made directly on a Game are credited to
+const SOLO_PLAYER = ""
+
+// Stats is how a player has done in a game. Moves counts every time they let go of a piece, Snaps the
+// joins those drops made and PercentComplete the share of all the joins needed to finish the puzzle that
+// they made. Elapsed is how long they have been playing for
+type Stats struct {
+	Player          string        `json:"player"`
+	Elapsed         time.Duration `json:"elapsed"`
+	Moves           int           `json:"moves"`
+	Snaps           int           `json:"snaps"`
+	Hints           int           `json:"hints"`
+	PercentComplete float64       `json:"percentComplete"`
+}
+
+type playerStats struct {
+	Stats
+	playing bool
+	since   time.Time
+}
+
+// Player makes moves on behalf of one player of a shared game so they are credited in their Stats
+type Player struct {
+	ID   string
+	game *Game
+}
+
+// Player returns the player with the given id, they start playing with their first move
+func (g *Game) Player(id string) Player {
+	return Player{ID: id, game: g}
+}
+
+func (p Player) Move(index int, pos image.Point) error {
+	return p.game.move(p.ID, index, pos)
+}
+
+func (p Player) Rotate(index, degrees int) error {
+	return p.game.rotate(p.ID, index, degrees)
+}
+
+func (p Player) Drop(index int) (bool, error) {
+	return p.game.drop(p.ID, index, nil)
+}
+
+// DropAvoiding is Drop but it does not snap the piece onto any group with a piece that held reports, such
+// as those other players are holding
+func (p Player) DropAvoiding(index int, held func(piece int) bool) (bool, error) {
+	return p.game.drop(p.ID, index, held)
+}
+
+func (p Player) Hint(strategy HintStrategy) (Hint, error) {
+	return p.game.hint(p.ID, strategy)
+}
+
+// Join starts the clock for the player
+func (g *Game) Join(player string) {
+	g.stats(player)
+}
+
+// Leave stops the clock for the player until they next join or make a move
+func (g *Game) Leave(player string) {
+	ps, ok := g.players[player]
+	if !ok || !ps.playing {
+		return
+	}
+	ps.Elapsed += time.Since(ps.since)
+	ps.playing = false
+}
+
+// PercentComplete is how many of the joins needed to finish the puzzle have been made
+func (g *Game) PercentComplete() float64 {
+	if len(g.states) < 2 {
+		if g.Complete() {
+			return 100
+		}
+		return 0
+	}
+	return 100 * float64(len(g.states)-g.groups.count()) / float64(len(g.states)-1)
+}
+
+// Stats returns how the player has done so far
+func (g *Game) Stats(player string) Stats {
+	ps, ok := g.players[player]
+	if !ok {
+		return Stats{Player: player}
+	}
+	stats := ps.Stats
+	if ps.playing {
+		stats.Elapsed += time.Since(ps.since)
+	}
+	if len(g.states) > 1 {
+		stats.PercentComplete = 100 * float64(stats.Snaps) / float64(len(g.states)-1)
+	}
+	return stats
+}
+
+// AllStats returns the Stats of everyone who has played, ordered by player
+func (g *Game) AllStats() []Stats {
+	ids := make([]string, 0, len(g.players))
+	for id := range g.players {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	all := make([]Stats, len(ids))
+	for i, id := range ids {
+		all[i] = g.Stats(id)
+	}
+	return all
+}
+
+// stats returns the player's running stats, starting their clock if it is not already going
+func (g *Game) stats(player string) *playerStats {
+	ps := g.playerStats(player)
+	if !ps.playing {
+		ps.playing = true
+		ps.since = time.Now()
+	}
+	return ps
+}
+
+func (g *Game) playerStats(player string) *playerStats {
+	ps, ok := g.players[player]
+	if !ok {
+		ps = &playerStats{Stats: Stats{Player: player}}
+		g.players[player] = ps
+	}
+	return ps
+}
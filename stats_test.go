@@ -0,0 +1,75 @@
+package jigsaw_test
+
+import (
+	"bytes"
+	"image"
+	"testing"
+	"time"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func TestStatsPerPlayer(t *testing.T) {
+	g := newGame(t)
+	alice, bob := g.Player("alice"), g.Player("bob")
+	assert.NoError(t, alice.Move(1, image.Pt(0, 0)))
+	_, err := alice.Drop(1)
+	assert.NoError(t, err)
+	assert.NoError(t, bob.Move(2, image.Pt(103, 2)))
+	snapped, err := bob.Drop(2)
+	assert.NoError(t, err)
+	assert.True(t, snapped, "expected piece to snap")
+	_, err = bob.Hint(nil)
+	assert.NoError(t, err)
+
+	a, b := g.Stats("alice"), g.Stats("bob")
+	assert.Equal(t, 1, a.Moves)
+	assert.Equal(t, 0, a.Snaps)
+	assert.Equal(t, 1, b.Moves)
+	assert.Equal(t, 1, b.Snaps)
+	assert.Equal(t, 1, b.Hints)
+	assert.InDelta(t, 100.0/3, b.PercentComplete, 0.001)
+	assert.InDelta(t, 100.0/3, g.PercentComplete(), 0.001)
+	assert.Len(t, g.AllStats(), 2)
+	assert.Equal(t, "alice", g.AllStats()[0].Player)
+
+	//undoing a snap takes it away from whoever made it
+	assert.NoError(t, g.Undo())
+	assert.Equal(t, 0, g.Stats("bob").Snaps)
+	assert.Equal(t, 0.0, g.PercentComplete())
+	assert.NoError(t, g.Redo())
+	assert.Equal(t, 1, g.Stats("bob").Snaps)
+}
+
+func TestStatsClockStopsWhenPlayerLeaves(t *testing.T) {
+	g := newGame(t)
+	assert.Equal(t, time.Duration(0), g.Stats("alice").Elapsed, "clock should not start before joining")
+	g.Join("alice")
+	time.Sleep(10 * time.Millisecond)
+	g.Leave("alice")
+	elapsed := g.Stats("alice").Elapsed
+	assert.True(t, elapsed >= 10*time.Millisecond, "expected the time played to be counted")
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, elapsed, g.Stats("alice").Elapsed, "clock should stop when the player leaves")
+}
+
+func TestStatsSaved(t *testing.T) {
+	g := newGame(t)
+	bob := g.Player("bob")
+	assert.NoError(t, bob.Move(1, image.Pt(0, 0)))
+	assert.NoError(t, bob.Move(2, image.Pt(100, 0)))
+	_, err := bob.Drop(2)
+	assert.NoError(t, err)
+	g.Leave("bob")
+	buf := &bytes.Buffer{}
+	assert.NoError(t, g.Save(buf))
+
+	loaded, err := jigsaw.LoadGame(g.Jigsaw, buf)
+	assert.NoError(t, err, "did not expect an error loading game")
+	assert.Equal(t, g.AllStats(), loaded.AllStats())
+
+	//the history remembers who made each move
+	assert.NoError(t, loaded.Undo())
+	assert.Equal(t, 0, loaded.Stats("bob").Snaps)
+}
@@ -0,0 +1,75 @@
+package jigsaw
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+)
+
+// MAX_TAB_JITTER is the furthest a tab can be moved from the middle of its side, as a fraction of the side
+const MAX_TAB_JITTER = 0.25
+
+var ErrBadTabRange = errors.New("min tab size should not be more than the max tab size")
+
+// validateTabs checks the builder's tab size range and jitter
+func (jb *JigsawBuilder) validateTabs() error {
+	if jb.MinTabSize < 0 || jb.MaxTabSize < 0 || jb.MaxTabSize > MAX_TAB_SIZE {
+		return fmt.Errorf("tab sizes should be between 0 and %v percent", MAX_TAB_SIZE)
+	}
+	if jb.MaxTabSize > 0 && jb.MinTabSize > jb.MaxTabSize {
+		return ErrBadTabRange
+	}
+	if jb.TabJitter < 0 || jb.TabJitter > MAX_TAB_JITTER {
+		return fmt.Errorf("tab jitter should be between 0 and %v", MAX_TAB_JITTER)
+	}
+	return nil
+}
+
+// varyTabs gives the tab of every shared edge a size from the builder's range and an offset along the
+// edge within its jitter, both drawn from the seed. A MinTabSize of 0 is taken to be MaxTabSize, so setting
+// only MaxTabSize makes every tab that size. The blank on the other side of the edge gets the same so the
+// two pieces still fit
+func (jb *JigsawBuilder) varyTabs(pieces []*Piece) {
+	if jb.MaxTabSize == 0 && jb.TabJitter == 0 {
+		return
+	}
+	min := jb.MinTabSize
+	if min == 0 {
+		min = jb.MaxTabSize
+	}
+	r := rand.New(rand.NewSource(jb.Seed))
+	eachEdge(pieces, func(_ *Piece, tab, blank *PieceJoint) {
+		//always draw both so a jigsaw's offsets do not change with whether its sizes vary
+		tab.Size = min + r.Float64()*(jb.MaxTabSize-min)
+		tab.Offset = (r.Float64()*2 - 1) * jb.TabJitter
+		if blank != nil {
+			blank.Size, blank.Offset = tab.Size, tab.Offset
+		}
+	})
+}
+
+// eachEdge calls fn with the piece with the external joint of every shared edge, that joint and the joint on
+// the other side of the edge. Edges are
+// visited in the order of the pieces and their joints so anything drawn from a seed is repeatable
+func eachEdge(pieces []*Piece, fn func(p *Piece, tab, blank *PieceJoint)) {
+	byIndex := make(map[int]*Piece, len(pieces))
+	for _, p := range pieces {
+		byIndex[p.Index] = p
+	}
+	for _, p := range pieces {
+		for i, joint := range p.Joints {
+			if !joint.External {
+				continue
+			}
+			var blank *PieceJoint
+			if other := byIndex[joint.Neighbour]; other != nil {
+				for j := range other.Joints {
+					if other.Joints[j].Neighbour == p.Index {
+						blank = &other.Joints[j]
+					}
+				}
+			}
+			fn(p, &p.Joints[i], blank)
+		}
+	}
+}
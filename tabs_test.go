@@ -0,0 +1,116 @@
+package jigsaw_test
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func variedJigsaw(t *testing.T, seed int64) jigsaw.Jigsaw {
+	return buildJigsaw(t, filled(300, 300, color.White), 9, jigsaw.JigsawPieceCutter{}, func(b *jigsaw.JigsawBuilder) {
+		b.Seed = seed
+		b.MinTabSize, b.MaxTabSize, b.TabJitter = 12, 20, 0.1
+	})
+}
+
+func jointOn(p *jigsaw.Piece, side int) jigsaw.PieceJoint {
+	for _, j := range p.Joints {
+		if j.Side == side {
+			return j
+		}
+	}
+	return jigsaw.PieceJoint{}
+}
+
+func alpha(p *jigsaw.Piece, x, y int) uint32 {
+	_, _, _, a := p.Image.At(x-p.Bounds.Min.X+p.Image.Bounds().Min.X, y-p.Bounds.Min.Y+p.Image.Bounds().Min.Y).RGBA()
+	return a
+}
+
+func TestTabsVaryFromSeed(t *testing.T) {
+	one, again, other := variedJigsaw(t, 5), variedJigsaw(t, 5), variedJigsaw(t, 6)
+	assert.Equal(t, one.Manifest().Pieces, again.Manifest().Pieces, "expected the same seed to cut the same tabs")
+	assert.NotEqual(t, one.Manifest().Pieces, other.Manifest().Pieces, "expected another seed to cut other tabs")
+
+	sizes := map[float64]bool{}
+	for _, p := range one.Pieces {
+		for _, j := range p.Joints {
+			assert.True(t, j.Size >= 12 && j.Size <= 20, "tab size %v out of range", j.Size)
+			assert.True(t, math.Abs(j.Offset) <= 0.1, "tab offset %v out of range", j.Offset)
+			sizes[j.Size] = true
+		}
+		if p.RightPieceIndex > 0 {
+			tab, blank := jointOn(p, jigsaw.RIGHT_SIDE), jointOn(one.Pieces[p.RightPieceIndex-1], jigsaw.LEFT_SIDE)
+			assert.Equal(t, tab.Size, blank.Size)
+			assert.Equal(t, tab.Offset, blank.Offset)
+		}
+		if p.BottomPieceIndex > 0 {
+			tab, blank := jointOn(p, jigsaw.BOTTOM_SIDE), jointOn(one.Pieces[p.BottomPieceIndex-1], jigsaw.TOP_SIDE)
+			assert.Equal(t, tab.Size, blank.Size)
+			assert.Equal(t, tab.Offset, blank.Offset)
+		}
+	}
+	assert.True(t, len(sizes) > 1, "expected the tabs to be different sizes")
+}
+
+func TestTabsPaddedByTheirDepth(t *testing.T) {
+	jig := variedJigsaw(t, 11)
+	for _, p := range jig.Pieces {
+		cell := image.Rect(p.Col*100, p.Row*100, (p.Col+1)*100, (p.Row+1)*100)
+		if p.RightPieceIndex > 0 {
+			cell.Max.X += jigsaw.Percentage(p, jointOn(p, jigsaw.RIGHT_SIDE).Size)
+		}
+		if p.BottomPieceIndex > 0 {
+			cell.Max.Y += jigsaw.Percentage(p, jointOn(p, jigsaw.BOTTOM_SIDE).Size)
+		}
+		assert.Equal(t, cell, p.Bounds, "unexpected bounds for %s", p.Name)
+	}
+}
+
+func TestTabsFitTheirBlanks(t *testing.T) {
+	jig := variedJigsaw(t, 21)
+	for _, left := range jig.Pieces {
+		if left.RightPieceIndex == 0 {
+			continue
+		}
+		right := jig.Pieces[left.RightPieceIndex-1]
+		edge := (left.Col + 1) * 100
+		depth := jigsaw.Percentage(left, jointOn(left, jigsaw.RIGHT_SIDE).Size)
+		tab := 0
+		for y := left.Row * 100; y < (left.Row+1)*100; y++ {
+			for x := edge; x < edge+depth; x++ {
+				inTab, inRight := alpha(left, x, y) > 0, alpha(right, x, y) > 0
+				assert.True(t, inTab != inRight, "%s and %s overlap or leave a gap at %d,%d", left.Name, right.Name, x, y)
+				if inTab {
+					tab++
+				}
+			}
+		}
+		assert.True(t, tab > 0, "expected %s to have a tab", left.Name)
+	}
+}
+
+func TestTabRangeValidated(t *testing.T) {
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(filled(100, 100, color.White), 4, jigsaw.JigsawPieceCutter{})
+	builder.MinTabSize, builder.MaxTabSize = 15, 10
+	_, err := builder.Build()
+	assert.Equal(t, jigsaw.ErrBadTabRange, err)
+	builder.MinTabSize, builder.MaxTabSize, builder.TabJitter = 0, 10, 0.5
+	_, err = builder.Build()
+	assert.Error(t, err, "expected too much jitter to be rejected")
+}
+
+func TestTabsAllMaxSizeWithoutAMin(t *testing.T) {
+	jig := buildJigsaw(t, filled(300, 300, color.White), 9, jigsaw.JigsawPieceCutter{}, func(b *jigsaw.JigsawBuilder) {
+		b.MaxTabSize = 15
+	})
+	for _, p := range jig.Pieces {
+		for _, j := range p.Joints {
+			assert.Equal(t, 15.0, j.Size, "expected every tab on %s to be the max size", p.Name)
+		}
+	}
+}
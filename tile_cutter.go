@@ -0,0 +1,122 @@
+package jigsaw
+
+import (
+	"image"
+	"math"
+)
+
+// the most of a side of a tiled piece that its tab can take up, shorter sides get smaller tabs
+const maxTabOfSide = 0.3
+
+// tileTab is the tab on a side shared by two tiled pieces, it belongs to the piece with Index owner
+type tileTab struct {
+	centre, along vec
+	radius        float64
+	owner         int
+}
+
+func (t tileTab) holds(p vec, square bool) bool {
+	d := p.sub(t.centre)
+	if !square {
+		return d.dot(d) < t.radius*t.radius
+	}
+	u, v := d.dot(t.along), d.dot(vec{-t.along.Y, t.along.X})
+	return math.Abs(u) < t.radius && math.Abs(v) < t.radius
+}
+
+// tileOwners works out which piece every pixel of a tiled board belongs to. A pixel belongs to the piece
+// with the nearest site, the lower Index on a tie, unless it falls in a tab on one of that piece's sides.
+// Every piece is cut with the same rule so the pieces always fit together
+type tileOwners struct {
+	tabs   map[int][]tileTab
+	cells  map[int]image.Rectangle
+	square bool
+}
+
+// tileTab works out the tab of the joint. Tabs are sized from the average size of a piece, unit, but kept
+// short of the ends of the side and moved along it by the joint's Offset
+func (jpc JigsawPieceCutter) tileTab(p *Piece, joint PieceJoint, unit float64) tileTab {
+	owner := p.Index
+	if !joint.External {
+		owner = joint.Neighbour
+	}
+	if len(joint.Edge) != 2 {
+		return tileTab{owner: owner}
+	}
+	a, b := pointVec(joint.Edge[0]), pointVec(joint.Edge[1])
+	length := b.sub(a).length()
+	along := b.sub(a).scale(1 / length)
+	radius := math.Min(jpc.jointSize(joint)/100*unit, maxTabOfSide*length)
+	shift := joint.Offset * length
+	if limit := length/2 - radius; limit <= 0 {
+		shift = 0
+	} else {
+		shift = math.Max(-limit, math.Min(limit, shift))
+	}
+	centre := a.add(b).scale(0.5).add(along.scale(shift))
+	return tileTab{centre: centre, along: along, radius: radius, owner: owner}
+}
+
+func (o tileOwners) owner(x, y int, candidates []*Piece) int {
+	p := pixelCentre(x, y)
+	var nearest *Piece
+	best := 0.0
+	for _, c := range candidates {
+		d := p.dist2(c.site)
+		if nearest == nil || d < best || (d == best && c.Index < nearest.Index) {
+			nearest, best = c, d
+		}
+	}
+	for _, t := range o.tabs[nearest.Index] {
+		if t.radius > 0 && t.holds(p, o.square) {
+			return t.owner
+		}
+	}
+	return nearest.Index
+}
+
+// cutTiles cuts pieces from a Tiling. Each piece's Bounds grow from its cell to take in its tabs
+func (jpc JigsawPieceCutter) cutTiles(from image.Image, pieces []*Piece) ([]*Piece, error) {
+	board := from.Bounds()
+	unit := math.Sqrt(float64(board.Dx()*board.Dy()) / float64(len(pieces)))
+	owners := tileOwners{tabs: map[int][]tileTab{}, cells: map[int]image.Rectangle{}, square: jpc.JointStyle == JOINT_SQUARE}
+	near := newBuckets(board, len(pieces))
+	for i, p := range pieces {
+		owners.cells[p.Index] = p.Bounds
+		near.add(p.Bounds, i)
+		for _, joint := range p.Joints {
+			owners.tabs[p.Index] = append(owners.tabs[p.Index], jpc.tileTab(p, joint, unit))
+		}
+	}
+	for _, p := range pieces {
+		depth := 0.0
+		for _, t := range owners.tabs[p.Index] {
+			if t.owner == p.Index {
+				depth = math.Max(depth, t.radius)
+			}
+		}
+		bounds := p.Bounds.Inset(-int(math.Ceil(depth))).Intersect(board)
+		//only pieces whose cells reach into the bounds can own any of it
+		candidates := make([]*Piece, 0)
+		for _, i := range near.near(bounds.Inset(-1)) {
+			if c := pieces[i]; owners.cells[c.Index].Overlaps(bounds.Inset(-1)) {
+				candidates = append(candidates, c)
+			}
+		}
+		img := image.NewNRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+		for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+			for x := bounds.Min.X; x < bounds.Max.X; x++ {
+				if owners.owner(x, y, candidates) == p.Index {
+					img.Set(x-bounds.Min.X, y-bounds.Min.Y, from.At(x, y))
+				}
+			}
+		}
+		p.Bounds = bounds
+		p.Points = []image.Point{bounds.Min, image.Pt(bounds.Max.X, bounds.Min.Y), image.Pt(bounds.Min.X, bounds.Max.Y), bounds.Max}
+		p.Image = img
+		if err := jpc.save(p); err != nil {
+			return nil, err
+		}
+	}
+	return pieces, nil
+}
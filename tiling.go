@@ -0,0 +1,314 @@
+package jigsaw
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"sort"
+)
+
+// Tiling lays the pieces of a jigsaw out in some other way than a grid. Each piece it returns is the part
+// of the board closest to a site of its own, with the Outline of that part and a joint on every side it shares with
+// another piece. The joints are marked and cut like those of a grid. A tiling of a regular pattern may only
+// be able to cut about numPieces
+type Tiling interface {
+	Tile(board image.Rectangle, numPieces int, seed int64) ([]*Piece, error)
+}
+
+var ErrWavyTiling = errors.New("wavy edges can only be cut on a grid")
+
+// vec is a position on the board between pixels
+type vec struct {
+	X, Y float64
+}
+
+func (a vec) add(b vec) vec       { return vec{a.X + b.X, a.Y + b.Y} }
+func (a vec) sub(b vec) vec       { return vec{a.X - b.X, a.Y - b.Y} }
+func (a vec) scale(s float64) vec { return vec{a.X * s, a.Y * s} }
+func (a vec) dot(b vec) float64   { return a.X*b.X + a.Y*b.Y }
+func (a vec) length() float64     { return math.Sqrt(a.dot(a)) }
+func (a vec) point() image.Point  { return image.Pt(int(math.Floor(a.X+0.5)), int(math.Floor(a.Y+0.5))) }
+func (a vec) floor() image.Point  { return image.Pt(int(math.Floor(a.X)), int(math.Floor(a.Y))) }
+func pointVec(p image.Point) vec  { return vec{float64(p.X), float64(p.Y)} }
+func pixelCentre(x, y int) vec    { return vec{float64(x) + 0.5, float64(y) + 0.5} }
+func (a vec) dist2(b vec) float64 { return a.sub(b).dot(a.sub(b)) }
+
+// cell is a convex part of the board. Side k runs from points[k] to the next point and labels[k] is the
+// Index of the site on its other side, 0 for the border of the board
+type cell struct {
+	points []vec
+	labels []int
+}
+
+func boardCell(board image.Rectangle) cell {
+	min, max := pointVec(board.Min), pointVec(board.Max)
+	return cell{
+		points: []vec{min, {max.X, min.Y}, max, {min.X, max.Y}},
+		labels: []int{0, 0, 0, 0},
+	}
+}
+
+// clip keeps the part of the cell that is closer to site than to other, the side it adds is labelled with
+// the other site's index
+func (c cell) clip(site, other vec, index int) cell {
+	mid, dir := site.add(other).scale(0.5), other.sub(site)
+	beyond := func(p vec) float64 { return p.sub(mid).dot(dir) }
+	out := cell{}
+	add := func(p vec, label int) {
+		if n := len(out.points); n > 0 && out.points[n-1].dist2(p) < 1e-12 {
+			out.labels[n-1] = label
+			return
+		}
+		out.points = append(out.points, p)
+		out.labels = append(out.labels, label)
+	}
+	for k, a := range c.points {
+		b := c.points[(k+1)%len(c.points)]
+		da, db := beyond(a), beyond(b)
+		cross := func() vec { return a.add(b.sub(a).scale(da / (da - db))) }
+		switch {
+		case da <= 0 && db <= 0:
+			add(a, c.labels[k])
+		case da <= 0:
+			add(a, c.labels[k])
+			add(cross(), index)
+		case db <= 0:
+			add(cross(), c.labels[k])
+		}
+	}
+	if n := len(out.points); n > 1 && out.points[n-1].dist2(out.points[0]) < 1e-12 {
+		out.points, out.labels = out.points[:n-1], out.labels[:n-1]
+	}
+	return out
+}
+
+// reach is the furthest the cell goes from the site, no site further than twice this away can cut it
+func (c cell) reach(site vec) float64 {
+	r := 0.0
+	for _, p := range c.points {
+		r = math.Max(r, p.dist2(site))
+	}
+	return math.Sqrt(r)
+}
+
+// centroid is the centre of mass of the cell, where Lloyd relaxation moves its site
+func (c cell) centroid() vec {
+	a, centre := 0.0, vec{}
+	for k, p := range c.points {
+		q := c.points[(k+1)%len(c.points)]
+		cross := p.X*q.Y - q.X*p.Y
+		a += cross
+		centre = centre.add(p.add(q).scale(cross))
+	}
+	if math.Abs(a) < 1e-9 {
+		return c.points[0]
+	}
+	return centre.scale(1 / (3 * a))
+}
+
+// bounds is the smallest rectangle of whole pixels holding the cell
+func (c cell) bounds() image.Rectangle {
+	min, max := c.points[0], c.points[0]
+	for _, p := range c.points {
+		min = vec{math.Min(min.X, p.X), math.Min(min.Y, p.Y)}
+		max = vec{math.Max(max.X, p.X), math.Max(max.Y, p.Y)}
+	}
+	return image.Rect(int(math.Floor(min.X)), int(math.Floor(min.Y)), int(math.Ceil(max.X)), int(math.Ceil(max.Y)))
+}
+
+// buckets splits the board into squares about the size of a piece, so the things on the board near a
+// place can be found without looking at all of them
+type buckets struct {
+	board      image.Rectangle
+	size       int
+	cols, rows int
+	items      [][]int
+}
+
+func newBuckets(board image.Rectangle, numPieces int) *buckets {
+	size := maxInt(1, int(math.Sqrt(float64(board.Dx()*board.Dy())/float64(maxInt(1, numPieces)))))
+	b := &buckets{board: board, size: size, cols: (board.Dx() + size - 1) / size, rows: (board.Dy() + size - 1) / size}
+	b.items = make([][]int, b.cols*b.rows)
+	return b
+}
+
+// span is the first and last column and row of the buckets r overlaps, clamped to the board
+func (b *buckets) span(r image.Rectangle) (int, int, int, int) {
+	r = r.Sub(b.board.Min)
+	clamp := func(v, n int) int { return maxInt(0, minInt(n-1, v)) }
+	return clamp(r.Min.X/b.size, b.cols), clamp((r.Max.X-1)/b.size, b.cols), clamp(r.Min.Y/b.size, b.rows), clamp((r.Max.Y-1)/b.size, b.rows)
+}
+
+// add puts the item in every bucket r overlaps
+func (b *buckets) add(r image.Rectangle, item int) {
+	c0, c1, r0, r1 := b.span(r)
+	for row := r0; row <= r1; row++ {
+		for col := c0; col <= c1; col++ {
+			b.items[row*b.cols+col] = append(b.items[row*b.cols+col], item)
+		}
+	}
+}
+
+// near returns each item in the buckets r overlaps once, in order, which is every item added with a
+// rectangle overlapping r and maybe some that are not
+func (b *buckets) near(r image.Rectangle) []int {
+	var items []int
+	seen := make(map[int]bool)
+	c0, c1, r0, r1 := b.span(r)
+	for row := r0; row <= r1; row++ {
+		for col := c0; col <= c1; col++ {
+			for _, item := range b.items[row*b.cols+col] {
+				if !seen[item] {
+					seen[item] = true
+					items = append(items, item)
+				}
+			}
+		}
+	}
+	sort.Ints(items)
+	return items
+}
+
+// byDistance sorts indexes into sites nearest to from first, the lower index first when two are as near
+type byDistance struct {
+	order []int
+	sites []vec
+	from  vec
+}
+
+func (d byDistance) Len() int      { return len(d.order) }
+func (d byDistance) Swap(i, j int) { d.order[i], d.order[j] = d.order[j], d.order[i] }
+func (d byDistance) Less(i, j int) bool {
+	a, b := d.from.dist2(d.sites[d.order[i]]), d.from.dist2(d.sites[d.order[j]])
+	if a != b {
+		return a < b
+	}
+	return d.order[i] < d.order[j]
+}
+
+// voronoiCells works out the cell of the board closest to each site, sites[i] being the site of the
+// piece with Index i+1
+func voronoiCells(board image.Rectangle, sites []vec) []cell {
+	near := newBuckets(board, len(sites))
+	for i, site := range sites {
+		near.add(image.Rectangle{Min: site.floor(), Max: site.floor().Add(image.Pt(1, 1))}, i)
+	}
+	cells := make([]cell, len(sites))
+	for i, site := range sites {
+		c := boardCell(board)
+		reach := c.reach(site)
+		clipped := map[int]bool{i: true}
+		//the sites are clipped against nearest first, those within radius being all in the buckets around
+		//the site, until the rest are too far away to cut the cell
+		for radius := near.size; ; radius *= 2 {
+			around := image.Rectangle{Min: site.floor(), Max: site.floor().Add(image.Pt(1, 1))}.Inset(-radius)
+			whole := around.Eq(around.Union(board))
+			var order []int
+			for _, j := range near.near(around) {
+				if !clipped[j] && (whole || site.dist2(sites[j]) <= float64(radius*radius)) {
+					order = append(order, j)
+				}
+			}
+			sort.Sort(byDistance{order, sites, site})
+			done := whole
+			for _, j := range order {
+				if math.Sqrt(site.dist2(sites[j])) > 2*reach {
+					done = true
+					break
+				}
+				clipped[j] = true
+				c = c.clip(site, sites[j], j+1)
+				reach = c.reach(site)
+			}
+			if done || float64(radius) >= 2*reach {
+				break
+			}
+		}
+		cells[i] = c
+	}
+	return cells
+}
+
+// sitePieces cuts the board into a piece around each site, two pieces are only joined where both their
+// cells have a side against the other
+func sitePieces(board image.Rectangle, sites []vec) ([]*Piece, error) {
+	seen := make(map[vec]bool, len(sites))
+	for _, site := range sites {
+		if seen[site] {
+			return nil, fmt.Errorf("image is too small to be cut into %d pieces", len(sites))
+		}
+		seen[site] = true
+	}
+	cells := voronoiCells(board, sites)
+	sides := make(map[[2]int]int)
+	for i, c := range cells {
+		for _, label := range c.labels {
+			if label > 0 {
+				sides[[2]int{minInt(i+1, label), maxInt(i+1, label)}]++
+			}
+		}
+	}
+	//both pieces use the ends of the side as the lower indexed piece sees them
+	edges := make(map[[2]int][]image.Point)
+	for i, c := range cells {
+		for k, label := range c.labels {
+			key := [2]int{i + 1, label}
+			if label <= i+1 || sides[key] != 2 {
+				continue
+			}
+			a, b := c.points[k].point(), c.points[(k+1)%len(c.points)].point()
+			if a != b {
+				edges[key] = []image.Point{a, b}
+			}
+		}
+	}
+	corners := []vec{pointVec(board.Min), {float64(board.Max.X), float64(board.Min.Y)}, pointVec(board.Max), {float64(board.Min.X), float64(board.Max.Y)}}
+	pieces := make([]*Piece, len(cells))
+	for i, c := range cells {
+		if len(c.points) < 3 {
+			return nil, fmt.Errorf("image is too small to be cut into %d pieces", len(sites))
+		}
+		bounds := c.bounds()
+		p := &Piece{
+			Index:  i + 1,
+			Name:   fmt.Sprintf("piece%d", i+1),
+			site:   sites[i],
+			Board:  board,
+			Bounds: bounds,
+			Width:  bounds.Dx(),
+			Height: bounds.Dy(),
+			Points: []image.Point{bounds.Min, image.Pt(bounds.Max.X, bounds.Min.Y), image.Pt(bounds.Min.X, bounds.Max.Y), bounds.Max},
+		}
+		for k, label := range c.labels {
+			//sides shorter than a pixel are dropped from the outline
+			if point, n := c.points[k].point(), len(p.Outline); n == 0 || p.Outline[n-1] != point {
+				p.Outline = append(p.Outline, point)
+			}
+			side := len(p.Outline) - 1
+			if label == 0 {
+				p.IsEdge = true
+				continue
+			}
+			if edge, ok := edges[[2]int{minInt(i+1, label), maxInt(i+1, label)}]; ok {
+				p.Joints = append(p.Joints, PieceJoint{Side: side, Neighbour: label, Edge: edge})
+			}
+		}
+		if n := len(p.Outline); n > 1 && p.Outline[n-1] == p.Outline[0] {
+			p.Outline = p.Outline[:n-1]
+			for j := range p.Joints {
+				p.Joints[j].Side %= len(p.Outline)
+			}
+		}
+		for _, point := range c.points {
+			for _, corner := range corners {
+				if point == corner {
+					p.IsCorner = true
+				}
+			}
+		}
+		p.IsCenter = !p.IsEdge
+		pieces[i] = p
+	}
+	return pieces, nil
+}
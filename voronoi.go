@@ -0,0 +1,71 @@
+package jigsaw
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+)
+
+// DEFAULT_RELAX is how many rounds of Lloyd relaxation the command line gives Voronoi layouts
+const DEFAULT_RELAX = 2
+
+// how many times a site is thrown before it is allowed closer to the others
+const siteThrows = 30
+
+// VoronoiTiling cuts irregular pieces around sites scattered across the board from the seed. Sites are
+// kept apart so no piece is tiny and Relax rounds of Lloyd relaxation then move each site to the centre
+// of its piece, making the pieces more even in size and shape the more rounds there are
+type VoronoiTiling struct {
+	Relax int
+}
+
+func (vt VoronoiTiling) Tile(board image.Rectangle, numPieces int, seed int64) ([]*Piece, error) {
+	if numPieces < 1 {
+		return nil, fmt.Errorf("can not cut %d pieces", numPieces)
+	}
+	if vt.Relax < 0 {
+		return nil, fmt.Errorf("relax should not be negative")
+	}
+	if board.Dx()*board.Dy() < numPieces {
+		return nil, fmt.Errorf("image is too small to be cut into %d pieces", numPieces)
+	}
+	sites := scatterSites(board, numPieces, rand.New(rand.NewSource(seed)))
+	for i := 0; i < vt.Relax; i++ {
+		for j, c := range voronoiCells(board, sites) {
+			sites[j] = c.centroid()
+		}
+	}
+	return sitePieces(board, sites)
+}
+
+// scatterSites throws n sites at the board, each at least a spacing away from the others. When a site
+// keeps landing too close the spacing is reduced so there is always room for all of them
+func scatterSites(board image.Rectangle, n int, r *rand.Rand) []vec {
+	spacing := 0.7 * math.Sqrt(float64(board.Dx()*board.Dy())/float64(n))
+	sites := make([]vec, 0, n)
+	for len(sites) < n {
+		throws := 0
+		for {
+			site := vec{float64(board.Min.X) + r.Float64()*float64(board.Dx()), float64(board.Min.Y) + r.Float64()*float64(board.Dy())}
+			if farFrom(site, sites, spacing) {
+				sites = append(sites, site)
+				break
+			}
+			if throws++; throws == siteThrows {
+				spacing *= 0.9
+				throws = 0
+			}
+		}
+	}
+	return sites
+}
+
+func farFrom(site vec, sites []vec, spacing float64) bool {
+	for _, s := range sites {
+		if site.dist2(s) < spacing*spacing {
+			return false
+		}
+	}
+	return true
+}
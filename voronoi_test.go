@@ -0,0 +1,160 @@
+package jigsaw_test
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+func voronoiJigsaw(t *testing.T, seed int64, relax int, cutter jigsaw.JigsawPieceCutter) jigsaw.Jigsaw {
+	return buildJigsaw(t, filled(240, 180, color.White), 20, cutter, func(b *jigsaw.JigsawBuilder) {
+		b.Seed = seed
+		b.Tiling = jigsaw.VoronoiTiling{Relax: relax}
+		b.MaxTabSize, b.TabJitter = 15, 0.15
+	})
+}
+
+func TestVoronoiPiecesFitTogether(t *testing.T) {
+	for _, cutter := range []jigsaw.JigsawPieceCutter{{}, {JointStyle: jigsaw.JOINT_SQUARE}, {JointStyle: jigsaw.JOINT_FLAT}} {
+		jig := voronoiJigsaw(t, 3, 2, cutter)
+		assert.Len(t, jig.Pieces, 20)
+		covered := coverage(jig)
+		bad := 0
+		for y := 0; y < 180; y++ {
+			for x := 0; x < 240; x++ {
+				if covered[image.Pt(x, y)] != 1 {
+					bad++
+				}
+			}
+		}
+		assert.Equal(t, 0, bad, "expected every pixel to be in exactly one %q piece", cutter.JointStyle)
+		assert.Len(t, covered, 240*180, "expected nothing outside the board")
+	}
+}
+
+func TestVoronoiJointsArePaired(t *testing.T) {
+	jig := voronoiJigsaw(t, 5, 1, jigsaw.JigsawPieceCutter{})
+	byIndex := map[int]*jigsaw.Piece{}
+	for _, p := range jig.Pieces {
+		byIndex[p.Index] = p
+	}
+	edges, corners := 0, 0
+	for _, p := range jig.Pieces {
+		assert.True(t, len(p.Outline) >= 3, "expected piece %d to have an outline", p.Index)
+		assert.NotEmpty(t, p.Joints, "expected piece %d to join another", p.Index)
+		for _, j := range p.Joints {
+			other := byIndex[j.Neighbour]
+			if !assert.NotNil(t, other, "expected piece %d's neighbour to exist", p.Index) {
+				continue
+			}
+			found := false
+			for _, oj := range other.Joints {
+				if oj.Neighbour == p.Index {
+					found = true
+					assert.NotEqual(t, j.External, oj.External, "expected one tab and one blank between %d and %d", p.Index, other.Index)
+					assert.Equal(t, j.Edge, oj.Edge)
+					assert.Equal(t, j.Size, oj.Size)
+					assert.Equal(t, j.Offset, oj.Offset)
+				}
+			}
+			assert.True(t, found, "expected piece %d to join %d back", other.Index, p.Index)
+		}
+		if p.IsEdge {
+			edges++
+		}
+		if p.IsCorner {
+			corners++
+		}
+		assert.Equal(t, !p.IsEdge, p.IsCenter)
+	}
+	assert.True(t, edges > 4 && edges < 20, "expected some but not all pieces on the edge, got %d", edges)
+	assert.True(t, corners >= 4, "expected the board's corners to be in pieces, got %d", corners)
+}
+
+// spread is how much the areas of the pieces vary, as a fraction of the mean
+func spread(jig jigsaw.Jigsaw) float64 {
+	areas := make([]float64, len(jig.Pieces))
+	mean := 0.0
+	for i, p := range jig.Pieces {
+		for k, a := range p.Outline {
+			b := p.Outline[(k+1)%len(p.Outline)]
+			areas[i] += float64(a.X*b.Y-b.X*a.Y) / 2
+		}
+		mean += areas[i] / float64(len(areas))
+	}
+	variance := 0.0
+	for _, a := range areas {
+		variance += (a - mean) * (a - mean) / float64(len(areas))
+	}
+	return math.Sqrt(variance) / mean
+}
+
+func TestVoronoiRelaxEvensPieces(t *testing.T) {
+	flat := jigsaw.JigsawPieceCutter{JointStyle: jigsaw.JOINT_FLAT}
+	rough, relaxed := voronoiJigsaw(t, 8, 0, flat), voronoiJigsaw(t, 8, 5, flat)
+	assert.True(t, spread(relaxed) < spread(rough), "expected relaxing to even out the pieces, %v >= %v", spread(relaxed), spread(rough))
+}
+
+func TestVoronoiRepeatableForSeed(t *testing.T) {
+	flat := jigsaw.JigsawPieceCutter{JointStyle: jigsaw.JOINT_FLAT}
+	a, b, c := voronoiJigsaw(t, 11, 1, flat), voronoiJigsaw(t, 11, 1, flat), voronoiJigsaw(t, 12, 1, flat)
+	assert.Equal(t, a.Manifest(), b.Manifest())
+	assert.NotEqual(t, a.Manifest(), c.Manifest())
+	assert.Equal(t, 0, a.Rows)
+	assert.Equal(t, a.Pieces[0].Outline, a.Manifest().Jigsaw().Pieces[0].Outline)
+}
+
+func TestVoronoiManyPiecesFitTogether(t *testing.T) {
+	jig := buildJigsaw(t, filled(400, 300, color.White), 600, jigsaw.JigsawPieceCutter{}, func(b *jigsaw.JigsawBuilder) {
+		b.Tiling = jigsaw.VoronoiTiling{Relax: 1}
+	})
+	assert.Len(t, jig.Pieces, 600)
+	covered := coverage(jig)
+	for y := 0; y < 300; y++ {
+		for x := 0; x < 400; x++ {
+			if covered[image.Pt(x, y)] != 1 {
+				t.Fatalf("expected %d,%d to be in exactly one piece", x, y)
+			}
+		}
+	}
+}
+
+func BenchmarkVoronoi2000Pieces(b *testing.B) {
+	img := filled(1200, 900, color.White)
+	for i := 0; i < b.N; i++ {
+		builder := jigsaw.NewJigsawBuilderWithPieceCutter(img, 2000, jigsaw.JigsawPieceCutter{})
+		builder.Tiling = jigsaw.VoronoiTiling{Relax: jigsaw.DEFAULT_RELAX}
+		if _, err := builder.Build(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestVoronoiGameSolves(t *testing.T) {
+	jig := voronoiJigsaw(t, 2, 2, jigsaw.JigsawPieceCutter{})
+	layout := &jigsaw.Layout{}
+	for i, p := range jig.Pieces {
+		layout.Placements = append(layout.Placements, jigsaw.Placement{Index: p.Index, Position: image.Pt(i*1000, 1000)})
+	}
+	g, err := jigsaw.NewGame(jig, layout)
+	assert.NoError(t, err)
+	solve(g)
+	assert.True(t, g.Complete(), "expected the pieces to snap together")
+}
+
+func TestVoronoiErrors(t *testing.T) {
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(filled(4, 4, color.White), 20, jigsaw.JigsawPieceCutter{})
+	builder.Tiling = jigsaw.VoronoiTiling{}
+	_, err := builder.Build()
+	assert.Error(t, err, "expected an error cutting more pieces than pixels")
+
+	builder = jigsaw.NewJigsawBuilderWithPieceCutter(filled(100, 100, color.White), 10, jigsaw.JigsawPieceCutter{})
+	builder.Tiling = jigsaw.VoronoiTiling{}
+	builder.EdgeStyle = jigsaw.EDGE_WAVY
+	_, err = builder.Build()
+	assert.Equal(t, jigsaw.ErrWavyTiling, err)
+}
@@ -0,0 +1,271 @@
+package jigsaw
+
+import (
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"path/filepath"
+
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/disintegration/imaging"
+)
+
+// WHIMSY_SIDE is the Side of the joints between a whimsy and the pieces around it, which are cut along
+// the whimsy's outline rather than on one of their sides
+const WHIMSY_SIDE = -1
+
+// Whimsy is a figure shaped piece cut out of the board, such as those in wooden puzzles. Mask is its
+// shape, the pixels that are more than half opaque, and At is where the top left of the mask goes on
+// the board. The pieces it lands on have its shape taken out of them
+type Whimsy struct {
+	Mask image.Image
+	At   image.Point
+}
+
+var ErrWhimsiesOverlap = errors.New("whimsies must not overlap")
+
+// Bounds is where the whimsy's mask is on the board
+func (w Whimsy) Bounds() image.Rectangle {
+	r := w.Mask.Bounds()
+	return r.Sub(r.Min).Add(w.At)
+}
+
+// covers reports whether the whimsy takes the pixel at x, y of the board
+func (w Whimsy) covers(x, y int) bool {
+	if !image.Pt(x, y).In(w.Bounds()) {
+		return false
+	}
+	min := w.Mask.Bounds().Min
+	_, _, _, a := w.Mask.At(x-w.At.X+min.X, y-w.At.Y+min.Y).RGBA()
+	return a >= 0x8000
+}
+
+// validateWhimsies checks every whimsy is on the board, has a shape and does not overlap another
+func validateWhimsies(board image.Rectangle, whimsies []Whimsy) error {
+	for i, w := range whimsies {
+		if w.Mask == nil {
+			return fmt.Errorf("whimsy %d has no mask", i+1)
+		}
+		if !w.Bounds().In(board) {
+			return fmt.Errorf("whimsy %d at %v is not on the board %v", i+1, w.Bounds(), board)
+		}
+		empty := true
+		r := w.Bounds()
+		for y := r.Min.Y; y < r.Max.Y && empty; y++ {
+			for x := r.Min.X; x < r.Max.X && empty; x++ {
+				empty = !w.covers(x, y)
+			}
+		}
+		if empty {
+			return fmt.Errorf("whimsy %d has an empty mask", i+1)
+		}
+		for _, other := range whimsies[:i] {
+			overlap := r.Intersect(other.Bounds())
+			for y := overlap.Min.Y; y < overlap.Max.Y; y++ {
+				for x := overlap.Min.X; x < overlap.Max.X; x++ {
+					if w.covers(x, y) && other.covers(x, y) {
+						return ErrWhimsiesOverlap
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// solid is an opaque image the size of the board. Cutting it gives the shape of each piece whatever is
+// see through in the image itself
+type solid struct {
+	bounds image.Rectangle
+}
+
+func (s solid) ColorModel() color.Model { return color.AlphaModel }
+func (s solid) Bounds() image.Rectangle { return s.bounds }
+func (s solid) At(x, y int) color.Color { return color.Opaque }
+
+// inPiece reports whether x, y of the board is part of a piece cut from a solid image
+func inPiece(p *Piece, x, y int) bool {
+	if p.Image == nil || !image.Pt(x, y).In(p.Bounds) {
+		return false
+	}
+	min := p.Image.Bounds().Min
+	_, _, _, a := p.Image.At(x-p.Bounds.Min.X+min.X, y-p.Bounds.Min.Y+min.Y).RGBA()
+	return a > 0
+}
+
+// pieceMap is which piece each pixel of the board is in, as the position of the piece in a list plus one
+// so that 0 is no piece
+type pieceMap struct {
+	board  image.Rectangle
+	labels []int
+}
+
+// newPieceMap maps the pieces cut from a solid image
+func newPieceMap(board image.Rectangle, pieces []*Piece) pieceMap {
+	m := pieceMap{board: board, labels: make([]int, board.Dx()*board.Dy())}
+	for i, p := range pieces {
+		r := p.Bounds.Intersect(board)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if inPiece(p, x, y) {
+					m.set(x, y, i+1)
+				}
+			}
+		}
+	}
+	return m
+}
+
+func (m pieceMap) set(x, y, label int) {
+	m.labels[(y-m.board.Min.Y)*m.board.Dx()+x-m.board.Min.X] = label
+}
+
+func (m pieceMap) at(x, y int) int {
+	if !image.Pt(x, y).In(m.board) {
+		return 0
+	}
+	return m.labels[(y-m.board.Min.Y)*m.board.Dx()+x-m.board.Min.X]
+}
+
+// addWhimsies takes pieces cut from a solid image and cuts each whimsy out of the pieces it lands on,
+// adding it as a piece of its own joined to every piece it touches
+func addWhimsies(board image.Rectangle, pieces []*Piece, whimsies []Whimsy) ([]*Piece, error) {
+	owners := newPieceMap(board, pieces)
+	for _, w := range whimsies {
+		r := w.Bounds()
+		index := len(pieces) + 1
+		wp := &Piece{
+			Index:    index,
+			Name:     fmt.Sprintf("piece%d", index),
+			IsWhimsy: true,
+			Board:    board,
+			Bounds:   r,
+			Width:    r.Dx(),
+			Height:   r.Dy(),
+			Points:   []image.Point{r.Min, image.Pt(r.Max.X, r.Min.Y), image.Pt(r.Min.X, r.Max.Y), r.Max},
+		}
+		side := r.Min.X == board.Min.X || r.Max.X == board.Max.X
+		end := r.Min.Y == board.Min.Y || r.Max.Y == board.Max.Y
+		wp.IsEdge = side || end
+		wp.IsCorner = side && end
+		wp.IsCenter = !wp.IsEdge
+		img := image.NewAlpha(image.Rect(0, 0, r.Dx(), r.Dy()))
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if w.covers(x, y) {
+					img.Set(x-r.Min.X, y-r.Min.Y, color.Opaque)
+				}
+			}
+		}
+		wp.Image = img
+		for _, p := range pieces {
+			if p.Bounds.Overlaps(r) {
+				if err := cutWhimsy(p, w); err != nil {
+					return nil, err
+				}
+			}
+		}
+		//join the whimsy to whatever is next to its edge
+		touching := map[int]bool{}
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if !w.covers(x, y) {
+					continue
+				}
+				for _, n := range []image.Point{{x, y - 1}, {x + 1, y}, {x, y + 1}, {x - 1, y}} {
+					if !w.covers(n.X, n.Y) {
+						touching[owners.at(n.X, n.Y)] = true
+					}
+				}
+			}
+		}
+		for i, p := range pieces {
+			if touching[i+1] {
+				wp.Joints = append(wp.Joints, PieceJoint{Side: WHIMSY_SIDE, External: true, Neighbour: p.Index})
+				p.Joints = append(p.Joints, PieceJoint{Side: WHIMSY_SIDE, Neighbour: index})
+			}
+		}
+		pieces = append(pieces, wp)
+		for y := r.Min.Y; y < r.Max.Y; y++ {
+			for x := r.Min.X; x < r.Max.X; x++ {
+				if w.covers(x, y) {
+					owners.set(x, y, len(pieces))
+				}
+			}
+		}
+	}
+	return pieces, nil
+}
+
+// cutWhimsy clears what the whimsy covers from the piece
+func cutWhimsy(p *Piece, w Whimsy) error {
+	if p.Image == nil {
+		return nil
+	}
+	img := imaging.Clone(p.Image)
+	left := false
+	for y := p.Bounds.Min.Y; y < p.Bounds.Max.Y; y++ {
+		for x := p.Bounds.Min.X; x < p.Bounds.Max.X; x++ {
+			if !inPiece(p, x, y) {
+				continue
+			}
+			if w.covers(x, y) {
+				img.Set(x-p.Bounds.Min.X, y-p.Bounds.Min.Y, color.Transparent)
+			} else {
+				left = true
+			}
+		}
+	}
+	if !left {
+		return fmt.Errorf("a whimsy covers the whole of piece %d", p.Index)
+	}
+	p.Image = img
+	return nil
+}
+
+// fillPiece replaces the solid shape of the piece with the image
+func fillPiece(from image.Image, p *Piece) {
+	if p.Image == nil {
+		return
+	}
+	img := image.NewNRGBA(image.Rect(0, 0, p.Bounds.Dx(), p.Bounds.Dy()))
+	for y := p.Bounds.Min.Y; y < p.Bounds.Max.Y; y++ {
+		for x := p.Bounds.Min.X; x < p.Bounds.Max.X; x++ {
+			if inPiece(p, x, y) {
+				img.Set(x-p.Bounds.Min.X, y-p.Bounds.Min.Y, from.At(x, y))
+			}
+		}
+	}
+	p.Image = img
+}
+
+// savePieces saves the pieces again where they were saved, and the whimsies and backs next to them
+func savePieces(pieces []*Piece) error {
+	dir := ""
+	for _, p := range pieces {
+		if p.Path != "" {
+			dir = filepath.Dir(p.Path)
+			break
+		}
+	}
+	if dir == "" {
+		return nil
+	}
+	for _, p := range pieces {
+		if p.IsWhimsy {
+			p.Path = filepath.Join(dir, p.Name+".png")
+		}
+		if p.Path != "" {
+			if err := imaging.Save(p.Image, p.Path); err != nil {
+				return errors.New("failed to save piece " + err.Error())
+			}
+		}
+		if p.Back != nil {
+			p.BackPath = filepath.Join(dir, p.Name+"_back.png")
+			if err := imaging.Save(p.Back, p.BackPath); err != nil {
+				return errors.New("failed to save piece " + err.Error())
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,157 @@
+package jigsaw_test
+
+import (
+	"image"
+	"image/color"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/maleck13/jigsaw"
+	"github.com/maleck13/jigsaw/Godeps/_workspace/src/github.com/stretchr/testify/assert"
+)
+
+// disc is a mask of a filled circle size pixels across
+func disc(size int) image.Image {
+	img := image.NewAlpha(image.Rect(0, 0, size, size))
+	r := float64(size) / 2
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			dx, dy := float64(x)+0.5-r, float64(y)+0.5-r
+			if dx*dx+dy*dy < r*r {
+				img.SetAlpha(x, y, color.Alpha{0xff})
+			}
+		}
+	}
+	return img
+}
+
+func whimsyBuilder(whimsies ...jigsaw.Whimsy) *jigsaw.JigsawBuilder {
+	builder := jigsaw.NewJigsawBuilderWithPieceCutter(filled(240, 240, color.White), 16, jigsaw.JigsawPieceCutter{})
+	builder.Whimsies = whimsies
+	return builder
+}
+
+func TestWhimsyCutOutOfPieces(t *testing.T) {
+	jig, err := whimsyBuilder(jigsaw.Whimsy{Mask: disc(50), At: image.Pt(95, 95)}).Build()
+	assert.NoError(t, err, "did not expect an error building")
+	assert.Len(t, jig.Pieces, 17)
+	whimsy := jig.Pieces[16]
+	assert.True(t, whimsy.IsWhimsy)
+	assert.True(t, whimsy.IsCenter)
+	assert.Equal(t, image.Rect(95, 95, 145, 145), whimsy.Bounds)
+	neighbours := whimsy.Neighbours()
+	sort.Ints(neighbours)
+	assert.Equal(t, []int{6, 7, 10, 11}, neighbours, "expected the whimsy to join the pieces it was cut from")
+	assert.Contains(t, jig.Pieces[5].Neighbours(), 17)
+	assert.True(t, jig.Manifest().Pieces[16].IsWhimsy)
+
+	covered := coverage(jig)
+	bad := 0
+	for y := 0; y < 240; y++ {
+		for x := 0; x < 240; x++ {
+			if covered[image.Pt(x, y)] != 1 {
+				bad++
+			}
+		}
+	}
+	assert.Equal(t, 0, bad, "expected every pixel to be in exactly one piece")
+	assert.Equal(t, uint32(0), alpha(jig.Pieces[5], 115, 115), "expected the whimsy to be cut out of piece 6")
+	assert.NotEqual(t, uint32(0), alpha(whimsy, 115, 115))
+
+	layout := &jigsaw.Layout{}
+	for i, p := range jig.Pieces {
+		layout.Placements = append(layout.Placements, jigsaw.Placement{Index: p.Index, Position: image.Pt(i*1000, 1000)})
+	}
+	g, err := jigsaw.NewGame(jig, layout)
+	assert.NoError(t, err)
+	solve(g)
+	assert.True(t, g.Complete(), "expected the whimsy to snap into place")
+}
+
+func TestWhimsiesJoinEachOther(t *testing.T) {
+	square := image.NewAlpha(image.Rect(0, 0, 40, 40))
+	for i := range square.Pix {
+		square.Pix[i] = 0xff
+	}
+	jig, err := whimsyBuilder(jigsaw.Whimsy{Mask: square, At: image.Pt(60, 60)}, jigsaw.Whimsy{Mask: square, At: image.Pt(100, 60)}).Build()
+	assert.NoError(t, err, "did not expect an error building")
+	assert.Len(t, jig.Pieces, 18)
+	first, second := jig.Pieces[16].Neighbours(), jig.Pieces[17].Neighbours()
+	sort.Ints(first)
+	sort.Ints(second)
+	assert.Equal(t, []int{2, 5, 6, 18}, first)
+	assert.Equal(t, []int{2, 3, 6, 7, 17}, second, "expected the second whimsy to join the first and the pieces around it")
+}
+
+func TestWhimsyOnTheBorder(t *testing.T) {
+	jig, err := whimsyBuilder(jigsaw.Whimsy{Mask: disc(40), At: image.Pt(0, 0)}, jigsaw.Whimsy{Mask: disc(40), At: image.Pt(100, 200)}).Build()
+	assert.NoError(t, err, "did not expect an error building")
+	corner, edge := jig.Pieces[16], jig.Pieces[17]
+	assert.True(t, corner.IsCorner, "expected a whimsy touching two sides of the board to be a corner")
+	assert.True(t, corner.IsEdge)
+	assert.False(t, edge.IsCorner, "expected a whimsy touching one side of the board not to be a corner")
+	assert.True(t, edge.IsEdge)
+	assert.False(t, edge.IsCenter)
+}
+
+func TestWhimsyRotates(t *testing.T) {
+	builder := whimsyBuilder(jigsaw.Whimsy{Mask: disc(40), At: image.Pt(10, 10)})
+	builder.Rotate = true
+	jig, err := builder.Build()
+	assert.NoError(t, err)
+	for _, p := range jig.Pieces {
+		for _, j := range p.Joints {
+			if j.Neighbour == 17 || p.IsWhimsy {
+				assert.Equal(t, jigsaw.WHIMSY_SIDE, j.Side, "expected joints to the whimsy to keep their side")
+			}
+		}
+	}
+}
+
+func TestWhimsyErrors(t *testing.T) {
+	for _, c := range []struct {
+		whimsies []jigsaw.Whimsy
+		message  string
+	}{
+		{[]jigsaw.Whimsy{{Mask: disc(50), At: image.Pt(200, 10)}}, "not on the board"},
+		{[]jigsaw.Whimsy{{Mask: image.NewAlpha(image.Rect(0, 0, 10, 10))}}, "empty"},
+		{[]jigsaw.Whimsy{{Mask: disc(50), At: image.Pt(10, 10)}, {Mask: disc(50), At: image.Pt(40, 40)}}, "overlap"},
+		{[]jigsaw.Whimsy{{Mask: filled(80, 80, color.White), At: image.Pt(50, 50)}}, "whole of piece 6"},
+		{[]jigsaw.Whimsy{{}}, "no mask"},
+	} {
+		_, err := whimsyBuilder(c.whimsies...).Build()
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), c.message)
+		}
+	}
+}
+
+func TestDecodeSVGSilhouette(t *testing.T) {
+	opaque := func(img image.Image, x, y int) bool {
+		_, _, _, a := img.At(x, y).RGBA()
+		return a > 0x8000
+	}
+	img, err := jigsaw.DecodeSVGSilhouette(strings.NewReader(`<svg xmlns="http://www.w3.org/2000/svg" width="40" height="40" viewBox="0 0 20 20">
+  <g><path d="M5,5 h10 v10 h-10 z"/></g>
+  <path d="M0 20 Q 2 16 4 20 C 4 18, 2 18, 0 20Z"/>
+</svg>`))
+	assert.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 40, 40), img.Bounds())
+	assert.True(t, opaque(img, 20, 20), "expected the scaled square to be filled")
+	assert.True(t, opaque(img, 11, 11))
+	assert.False(t, opaque(img, 5, 5))
+	assert.False(t, opaque(img, 35, 20))
+
+	for _, bad := range []string{
+		`<svg width="10" height="10"><path d="M0 0 A 5 5 0 0 1 10 10"/></svg>`,
+		`<svg width="10" height="10"><path transform="scale(2)" d="M0 0 L10 10 L0 10 Z"/></svg>`,
+		`<svg width="10" height="10"><path d="M0 0 L10"/></svg>`,
+		`<svg width="10" height="10"></svg>`,
+		`<svg><path d="M0 0 L10 10 L0 10 Z"/></svg>`,
+		`<html></html>`,
+	} {
+		_, err := jigsaw.DecodeSVGSilhouette(strings.NewReader(bad))
+		assert.Error(t, err, "expected an error decoding %s", bad)
+	}
+}